@@ -12,6 +12,8 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -22,6 +24,7 @@ import (
 	sidlog "github.com/siddontang/go-log/log"
 	"golang.org/x/sys/unix"
 	"gopkg.in/natefinch/lumberjack.v2"
+	"gopkg.in/yaml.v3"
 
 	"github.com/pparshin/go-mysql-tarantool/internal/adapter"
 	"github.com/pparshin/go-mysql-tarantool/internal/bridge"
@@ -37,6 +40,9 @@ var (
 
 var (
 	configPath = flag.String("config", "", "Config file path")
+	verifyMode = flag.Bool("verify", false, "Compare MySQL tables with Tarantool spaces and exit")
+	genSchema  = flag.Bool("gen-schema", false, "Print Lua code creating Tarantool spaces of the mappings and exit")
+	genMapping = flag.String("gen-mappings", "", "Print the mappings of all the tables of MySQL schema and exit")
 )
 
 func main() {
@@ -46,9 +52,47 @@ func main() {
 		log.Fatal().Err(err).Msgf("failed to read config")
 	}
 
-	logger := initLogger(cfg)
+	// Generated code is printed to stdout, so the logs are written to stderr.
+	var logOutput io.Writer = os.Stdout
+	if *genSchema || *genMapping != "" {
+		logOutput = os.Stderr
+	}
+
+	logger := initLogger(cfg, logOutput)
 	logger.Info().Msgf("starting replicator %s, commit %s, built at %s", version, commit, buildDate)
 
+	if *genSchema {
+		lua, err := bridge.SpacesSchema(cfg, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("could not generate spaces schema")
+		}
+
+		fmt.Print(lua)
+
+		return
+	}
+
+	if *genMapping != "" {
+		mappings, err := bridge.GenerateMappings(cfg, *genMapping, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("could not generate mappings")
+		}
+
+		out := struct {
+			Mappings []config.Mapping `yaml:"mappings"`
+		}{
+			Mappings: mappings,
+		}
+
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(&out); err != nil {
+			logger.Fatal().Err(err).Msg("could not encode mappings")
+		}
+
+		return
+	}
+
 	metrics.Init()
 
 	b, err := bridge.New(cfg, logger)
@@ -56,9 +100,15 @@ func main() {
 		logger.Fatal().Err(err).Msg("could not establish bridge from MySQL to Tarantool")
 	}
 
+	if *verifyMode {
+		os.Exit(runVerify(b, logger))
+	}
+
 	healthHd := initHealthHandler(cfg.App.Health, b)
 	aboutHd := initAboutHandler(version, commit, buildDate)
-	server := initHTTPServer(cfg.App.ListenAddr, healthHd, aboutHd)
+	resyncHd := initResyncHandler(b)
+	verifyHd := initVerifyHandler(b)
+	server := initHTTPServer(cfg.App.ListenAddr, healthHd, aboutHd, resyncHd, verifyHd)
 	go func() {
 		logger.Info().Msgf("listening on %s", cfg.App.ListenAddr)
 
@@ -97,7 +147,41 @@ func main() {
 	}
 }
 
-func initLogger(cfg *config.Config) zerolog.Logger {
+// runVerify compares the tables and returns the exit code:
+// 0 if the data is consistent, 1 if the difference is found, 2 on error.
+func runVerify(b *bridge.Bridge, logger zerolog.Logger) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	reports, err := b.Verify(ctx)
+	if err != nil {
+		logger.Err(err).Msg("verification failed")
+
+		return 2
+	}
+
+	code := 0
+	for _, r := range reports {
+		event := logger.Info()
+		if !r.Consistent() {
+			event = logger.Warn()
+			code = 1
+		}
+
+		event.EmbedObject(r).Msg("verification done")
+	}
+
+	return code
+}
+
+func initLogger(cfg *config.Config, out io.Writer) zerolog.Logger {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
 	loggingCfg := cfg.App.Logging
@@ -109,7 +193,7 @@ func initLogger(cfg *config.Config) zerolog.Logger {
 	}
 
 	writers := make([]io.Writer, 0, 1)
-	writers = append(writers, os.Stdout)
+	writers = append(writers, out)
 
 	if loggingCfg.SysLogEnabled {
 		w, err := syslog.New(syslog.LOG_INFO, "mysql-tarantool-replicator")
@@ -160,7 +244,7 @@ func newRollingLogFile(cfg *config.Logging) (io.Writer, error) {
 	}, nil
 }
 
-func initHTTPServer(addr string, healthHd, aboutHd http.Handler) *http.Server {
+func initHTTPServer(addr string, healthHd, aboutHd, resyncHd, verifyHd http.Handler) *http.Server {
 	server := &http.Server{
 		Addr:         addr,
 		ReadTimeout:  5 * time.Second, //nolint:gomnd
@@ -170,6 +254,8 @@ func initHTTPServer(addr string, healthHd, aboutHd http.Handler) *http.Server {
 	http.Handle("/metrics", promhttp.Handler())
 	http.Handle("/health", healthHd)
 	http.Handle("/about", aboutHd)
+	http.Handle("/resync", resyncHd)
+	http.Handle("/verify", verifyHd)
 
 	return server
 }
@@ -196,7 +282,10 @@ func initHealthHandler(cfg config.Health, b *bridge.Bridge) http.Handler {
 				func(ctx context.Context) error {
 					dumping := b.Dumping()
 					if dumping {
-						return errors.New("replicator has not yet finished dump process")
+						p := b.DumpProgress()
+
+						return fmt.Errorf("replicator has not yet finished dump process: %d of %d tables done, %d rows copied",
+							p.TablesDone, p.Tables, p.Rows)
 					}
 
 					running := b.Running()
@@ -236,3 +325,77 @@ func initAboutHandler(version, commit, buildDate string) http.Handler {
 		_, _ = w.Write(aboutStr)
 	})
 }
+
+// initResyncHandler returns the handler to copy the table again, e.g.:
+// POST /resync?table=city.users.
+func initResyncHandler(b *bridge.Bridge) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		table := r.URL.Query().Get("table")
+		parts := strings.SplitN(table, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			http.Error(w, "table must be set as schema.table", http.StatusBadRequest)
+
+			return
+		}
+
+		err := b.Resync(parts[0], parts[1])
+		switch {
+		case errors.Is(err, bridge.ErrRuleNotExist):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, bridge.ErrResyncInProgress), errors.Is(err, bridge.ErrNotRunning),
+			errors.Is(err, bridge.ErrSharedSpace):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprintf(w, "resync of %s started\n", table)
+		}
+	})
+}
+
+// initVerifyHandler returns the handler to verify the table in background, e.g.:
+// POST /verify?table=city.users&repair=true.
+// All the tables are verified if the table is not set.
+func initVerifyHandler(b *bridge.Bridge) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		var schema, table string
+		if name := r.URL.Query().Get("table"); name != "" {
+			parts := strings.SplitN(name, ".", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				http.Error(w, "table must be set as schema.table", http.StatusBadRequest)
+
+				return
+			}
+
+			schema, table = parts[0], parts[1]
+		}
+
+		repair, _ := strconv.ParseBool(r.URL.Query().Get("repair"))
+
+		err := b.StartVerify(schema, table, repair)
+		switch {
+		case errors.Is(err, bridge.ErrRuleNotExist):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, bridge.ErrNotRunning):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = fmt.Fprintln(w, "verification started")
+		}
+	})
+}
@@ -6,14 +6,16 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/etherlabsio/healthcheck v0.0.0-20191224061800-dd3d2fd8c3f6
 	github.com/philhofer/fwd v1.1.0 // indirect
+	github.com/pingcap/parser v0.0.0-20190506092653-e336082eb825
 	github.com/prometheus/client_golang v1.8.0
 	github.com/rs/zerolog v1.20.0
 	github.com/satori/go.uuid v1.2.0 // indirect
+	github.com/shopspring/decimal v0.0.0-20180709203117-cd690d0c9e24
 	github.com/siddontang/go v0.0.0-20180604090527-bdc77568d726
 	github.com/siddontang/go-log v0.0.0-20180807004314-8d05993dda07
 	github.com/siddontang/go-mysql v1.1.0
 	github.com/stretchr/testify v1.6.1
-	github.com/tinylib/msgp v1.1.2 // indirect
+	github.com/tinylib/msgp v1.1.2
 	github.com/viciious/go-tarantool v0.0.0-20201014090959-d4e1044f393b
 	go.uber.org/atomic v1.5.0
 	golang.org/x/sys v0.0.0-20201029080932-201ba4db2418
@@ -2,7 +2,12 @@ package bridge
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/siddontang/go-mysql/schema"
 )
 
@@ -30,28 +35,183 @@ const (
 type castType int
 
 const (
-	castNone     castType = iota // do not cast
-	castUnsigned                 // unsigned
+	castNone            castType = iota // do not cast
+	castUnsigned                        // unsigned
+	castInteger                         // integer
+	castNumber                          // integer or float
+	castDouble                          // float
+	castBoolean                         // boolean
+	castString                          // string
+	castUnixTimestamp                   // seconds since the epoch, integer
+	castUnixTimestampMs                 // milliseconds since the epoch, integer
+	castISO8601                         // ISO 8601 string
+	castDatetime                        // Tarantool datetime
+	castJSON                            // decoded JSON document
+	castDecimal                         // Tarantool decimal
+	castDecimalString                   // exact decimal notation
+	castUUID                            // Tarantool uuid
+	castArray                           // array of POINT coordinates or SET labels
+	castMap                             // map of POINT coordinates
 )
 
-func castTypeFromString(str string) castType {
+var castTypeNames = map[castType]string{
+	castUnsigned:        "unsigned",
+	castInteger:         "integer",
+	castNumber:          "number",
+	castDouble:          "double",
+	castBoolean:         "boolean",
+	castString:          "string",
+	castUnixTimestamp:   "unix_timestamp",
+	castUnixTimestampMs: "unix_timestamp_ms",
+	castISO8601:         "iso8601",
+	castDatetime:        "datetime",
+	castJSON:            "json",
+	castDecimal:         "decimal",
+	castDecimalString:   "decimal_string",
+	castUUID:            "uuid",
+	castArray:           "array",
+	castMap:             "map",
+}
+
+func castTypeFromString(str string) (castType, error) {
+	if str == "" {
+		return castNone, nil
+	}
+
+	for t, name := range castTypeNames {
+		if name == str {
+			return t, nil
+		}
+	}
+
+	return castNone, fmt.Errorf("unknown cast type: %s", str)
+}
+
+func (t castType) String() string {
+	if name, ok := castTypeNames[t]; ok {
+		return name
+	}
+
+	return "none"
+}
+
+// castApplicable reports whether the values of the column type may be cast.
+// The numeric casts accept the numbers and the numeric strings, e.g. DECIMAL
+// read from the dump, the temporal casts accept the temporal types only.
+func castApplicable(t castType, vType attrType) bool {
+	switch t {
+	case castNone, castString:
+		return true
+	case castUnsigned, castInteger, castNumber:
+		switch vType {
+		case typeNumber, typeMediumInt, typeFloat, typeDecimal, typeBit, typeString, typeEnum, typeSet:
+			return true
+		}
+	case castDouble, castBoolean:
+		switch vType {
+		case typeNumber, typeMediumInt, typeFloat, typeDecimal, typeBit, typeString:
+			return true
+		}
+	case castUnixTimestamp, castUnixTimestampMs:
+		switch vType {
+		case typeDatetime, typeTimestamp, typeDate, typeTime:
+			return true
+		}
+	case castISO8601, castDatetime:
+		switch vType {
+		case typeDatetime, typeTimestamp, typeDate:
+			return true
+		}
+	case castJSON:
+		return vType == typeJSON || vType == typeString
+	case castDecimal, castDecimalString:
+		switch vType {
+		case typeDecimal, typeNumber, typeMediumInt, typeString:
+			return true
+		}
+	case castUUID:
+		return vType == typeBinary || vType == typeString
+	case castArray:
+		return vType == typePoint || vType == typeSet
+	case castMap:
+		return vType == typePoint
+	}
+
+	return false
+}
+
+type invalidPolicy int
+
+const (
+	invalidFail    invalidPolicy = iota // stop the replication
+	invalidClamp                        // replace by the nearest value in range
+	invalidDefault                      // replace by on_null value
+	invalidLog                          // skip the row and log it
+)
+
+func invalidPolicyFromString(str string) (invalidPolicy, error) {
 	switch str {
-	case "unsigned":
-		return castUnsigned
+	case "", "fail":
+		return invalidFail, nil
+	case "clamp":
+		return invalidClamp, nil
+	case "default":
+		return invalidDefault, nil
+	case "log":
+		return invalidLog, nil
 	default:
-		return castNone
+		return invalidFail, fmt.Errorf("unknown invalid value policy: %s", str)
 	}
 }
 
+func (p invalidPolicy) String() string {
+	switch p {
+	case invalidClamp:
+		return "clamp"
+	case invalidDefault:
+		return "default"
+	case invalidLog:
+		return "log"
+	default:
+		return "fail"
+	}
+}
+
+// conversionError is the value which can not be cast exactly.
+type conversionError struct {
+	value   interface{}
+	target  string      // type the value is cast to
+	reason  string      // e.g. "negative", "out of range", "fractional part"
+	clamped interface{} // the nearest value in range, nil if there is no such value
+}
+
+func (e *conversionError) Error() string {
+	if s, ok := e.value.(string); ok {
+		return fmt.Sprintf("could not cast %q to %s: %s", s, e.target, e.reason)
+	}
+
+	return fmt.Sprintf("could not cast %v to %s: %s", e.value, e.target, e.reason)
+}
+
 // attribute represents MySQL column mapped to Tarantool.
 type attribute struct {
-	colIndex uint64      // column sequence number in MySQL table
-	tupIndex uint64      // attribute sequence number in Tarantool tuple
-	name     string      // unique attribute name
-	vType    attrType    // value type stored in the column
-	cType    castType    // value must be casted to this type
-	onNull   interface{} // replace null by this value
-	unsigned bool        // whether attribute contains unsigned number or not
+	colIndex  uint64         // column sequence number in MySQL table
+	tupIndex  uint64         // attribute sequence number in Tarantool tuple
+	name      string         // unique attribute name
+	vType     attrType       // value type stored in the column
+	cType     castType       // value must be casted to this type
+	onNull    interface{}    // replace null by this value
+	unsigned  bool           // whether attribute contains unsigned number or not
+	dropped   bool           // column is dropped from MySQL table, the value is null
+	strict    bool           // negative values cast to unsigned are invalid
+	onInvalid invalidPolicy  // applied to the values which can not be cast exactly in strict mode
+	location  *time.Location // time zone of DATETIME and DATE values, UTC if nil
+	fsp       int            // fractional seconds precision of the temporal column
+	field     string         // name of the space field extracted from JSON column
+	path      []jsonPathStep // JSON path extracted from the column, nil if the column is not extracted
+	scale     int32          // digits after the point of DECIMAL column
+	binaryLen int            // length of BINARY column, zero for the other types
+	labels    []string       // values of ENUM or SET column
 }
 
 func newAttr(table *schema.Table, tupIndex uint64, name string) (*attribute, error) {
@@ -60,78 +220,282 @@ func newAttr(table *schema.Table, tupIndex uint64, name string) (*attribute, err
 		return nil, fmt.Errorf("column not found, name: schema: %s, table: %s, name: %s", table.Schema, table.Name, name)
 	}
 
-	col := table.Columns[idx]
-
-	return &attribute{
+	attr := &attribute{
 		colIndex: uint64(idx),
 		tupIndex: tupIndex,
-		name:     col.Name,
-		vType:    attrType(col.Type),
 		cType:    castNone,
-		unsigned: col.IsUnsigned,
-	}, nil
+	}
+	attr.setColumn(&table.Columns[idx])
+
+	return attr, nil
 }
 
 func newAttrsFromPKs(table *schema.Table) []*attribute {
 	pks := make([]*attribute, 0)
 	for i, pki := range table.PKColumns {
-		col := table.GetPKColumn(pki)
-		pks = append(pks, &attribute{
+		attr := &attribute{
 			colIndex: uint64(pki),
 			tupIndex: uint64(i),
-			name:     col.Name,
-			vType:    attrType(col.Type),
 			cType:    castNone,
-			unsigned: col.IsUnsigned,
-		})
+		}
+		attr.setColumn(table.GetPKColumn(pki))
+
+		pks = append(pks, attr)
 	}
 
 	return pks
 }
 
-func (a *attribute) castTo(t castType) {
+// setColumn sets the properties of the attribute following the column type.
+func (a *attribute) setColumn(col *schema.TableColumn) {
+	a.name = col.Name
+	a.vType = attrType(col.Type)
+	a.unsigned = col.IsUnsigned
+	a.fsp = fractionalDigits(col.RawType)
+	a.scale = decimalScale(col.RawType)
+	a.binaryLen = binaryLength(col.RawType)
+
+	switch a.vType {
+	case typeEnum:
+		a.labels = col.EnumValues
+	case typeSet:
+		a.labels = col.SetValues
+	default:
+		a.labels = nil
+	}
+}
+
+func (a *attribute) castTo(t castType) error {
+	if !castApplicable(t, a.vType) {
+		return fmt.Errorf("cast %s is not applicable to column %s", t, a.name)
+	}
+
 	a.cType = t
+
+	return nil
 }
 
 func (a *attribute) fetchValue(row []interface{}) (interface{}, error) {
-	if a.colIndex >= uint64(len(row)) {
-		return nil, fmt.Errorf("column index (%d) equals or greater than row length (%d)", a.colIndex, len(row))
-	}
+	var value interface{}
+	if !a.dropped {
+		if a.colIndex >= uint64(len(row)) {
+			return nil, fmt.Errorf("column index (%d) equals or greater than row length (%d)", a.colIndex, len(row))
+		}
 
-	value := row[a.colIndex]
+		value = row[a.colIndex]
+	}
 
-	if value == nil && a.onNull != nil {
+	if value == nil && a.onNull != nil && a.path == nil {
 		value = a.onNull
 	}
 
-	if a.shouldCastToUInt64(value) {
-		v, err := toUint64(value)
+	v, err := a.cast(value)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", a.name, err)
+	}
+
+	// The missing JSON path is null as well.
+	if v == nil && a.path != nil {
+		return a.onNull, nil
+	}
+
+	return v, nil
+}
+
+// defaultValue returns on_null value of the attribute cast to its type.
+// The values of the extracted JSON paths are not cast.
+func (a *attribute) defaultValue() (interface{}, error) {
+	if a.path != nil {
+		return a.onNull, nil
+	}
+
+	return a.cast(a.onNull)
+}
+
+// sourceValue converts the value of the tuple key back to the value of the column,
+// so the rows may be selected from MySQL by the tuple keys.
+func (a *attribute) sourceValue(v interface{}) interface{} {
+	switch a.cType {
+	case castUUID:
+		b, ok := uuidBytes(v)
+		if !ok {
+			return v
+		}
+		if a.vType == typeBinary && a.binaryLen != 36 {
+			return string(b)
+		}
+
+		var u uuidValue
+		copy(u[:], b)
+
+		return u.String()
+	case castDecimal:
+		if d, ok := extensionDecimal(v); ok {
+			return formatDecimal(d, a.scale)
+		}
+	case castUnixTimestamp, castUnixTimestampMs, castISO8601, castDatetime:
+		if s, ok := a.sourceTemporal(v); ok {
+			return s
+		}
+	}
+
+	return v
+}
+
+// fieldName returns the name of the space field of the attribute.
+func (a *attribute) fieldName() string {
+	if a.field != "" {
+		return a.field
+	}
+
+	return a.name
+}
+
+// cast converts the value to the type of the attribute.
+// Returns *conversionError if the value can not be cast exactly.
+func (a *attribute) cast(value interface{}) (interface{}, error) {
+	// Null is not cast.
+	if value == nil {
+		return nil, nil
+	}
+
+	// ENUM, SET and POINT values are represented the same way
+	// whether they are read from the binlog or from the dump.
+	switch a.vType {
+	case typeEnum:
+		return a.castEnum(value)
+	case typeSet:
+		return a.castSet(value)
+	case typePoint:
+		if a.cType != castNone {
+			return a.castPoint(value)
+		}
+	case typeBit:
+		v, err := toBitValue(value)
 		if err != nil {
 			return nil, err
 		}
+		value = v
+	}
 
-		return v, nil
+	// DECIMAL values are read exactly, the other casts get them as before.
+	if d, ok := value.(decimal.Decimal); ok {
+		switch a.cType {
+		case castDecimal, castDecimalString:
+		case castString:
+			value = d.String()
+		default:
+			value, _ = d.Float64()
+		}
 	}
 
-	return value, nil
+	switch a.cType {
+	case castNone:
+		if !a.unsigned || (a.vType != typeNumber && a.vType != typeMediumInt) {
+			return value, nil
+		}
+
+		return toUint64(value)
+	case castUnsigned:
+		if a.strict && isNegative(value) {
+			return nil, &conversionError{value: value, target: "uint64", reason: "negative", clamped: uint64(0)}
+		}
+
+		return toUnsigned(value)
+	case castInteger:
+		return toInt64(value)
+	case castNumber:
+		return toNumber(value)
+	case castDouble:
+		return toDouble(value)
+	case castBoolean:
+		return toBool(value)
+	case castString:
+		return toString(value)
+	case castUnixTimestamp:
+		return toUnixTime(value, a.vType, a.timeZone(), time.Second)
+	case castUnixTimestampMs:
+		return toUnixTime(value, a.vType, a.timeZone(), time.Millisecond)
+	case castISO8601:
+		return toISO8601(value, a.vType, a.timeZone(), a.fsp)
+	case castDatetime:
+		return toDatetime(value, a.timeZone())
+	case castJSON:
+		v, err := toJSON(value)
+		if err != nil || a.path == nil {
+			return v, err
+		}
+
+		return extractJSON(v, a.path), nil
+	case castDecimal:
+		return toDecimal(value, a.scale)
+	case castDecimalString:
+		return toDecimalString(value, a.scale)
+	case castUUID:
+		return toUUID(value, a.vType, a.binaryLen)
+	default:
+		return value, nil
+	}
 }
 
-func (a *attribute) shouldCastToUInt64(value interface{}) bool {
-	if a.cType == castUnsigned {
-		return true
+// timeZone returns the location the temporal values are read in.
+// TIMESTAMP values are always read in UTC.
+func (a *attribute) timeZone() *time.Location {
+	if a.location == nil || a.vType == typeTimestamp {
+		return time.UTC
 	}
 
-	if value == nil {
-		return false
+	return a.location
+}
+
+// toBitValue returns BIT value as unsigned number. The binlog holds the numbers,
+// the dump holds the big-endian bytes.
+func toBitValue(i interface{}) (interface{}, error) {
+	var b []byte
+	switch v := i.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		// The number or the replacement of null.
+		return i, nil
+	}
+
+	if len(b) > 8 {
+		return nil, &conversionError{value: fmt.Sprintf("%x", b), target: "bit", reason: "longer than 64 bits"}
 	}
 
-	if !a.unsigned {
-		return false
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+
+	return n, nil
+}
+
+func isNegative(i interface{}) bool {
+	switch i := i.(type) {
+	case int:
+		return i < 0
+	case int8:
+		return i < 0
+	case int16:
+		return i < 0
+	case int32:
+		return i < 0
+	case int64:
+		return i < 0
+	case float32:
+		return i < 0
+	case float64:
+		return i < 0
 	}
 
-	return a.vType == typeNumber || a.vType == typeMediumInt
+	return false
 }
 
+// toUint64 casts the integers and the floats without fractional part to uint64.
 func toUint64(i interface{}) (uint64, error) {
 	switch i := i.(type) {
 	case int:
@@ -154,7 +518,251 @@ func toUint64(i interface{}) (uint64, error) {
 		return uint64(i), nil
 	case uint64:
 		return i, nil
+	case float32:
+		return floatToUint64(float64(i))
+	case float64:
+		return floatToUint64(i)
 	}
 
 	return 0, fmt.Errorf("could not cast %T to uint64: %v", i, i)
 }
+
+// toUnsigned casts the value to uint64, the numeric strings are parsed.
+func toUnsigned(i interface{}) (uint64, error) {
+	switch i := i.(type) {
+	case string:
+		return parseUint64(i)
+	case []byte:
+		return parseUint64(string(i))
+	}
+
+	return toUint64(i)
+}
+
+func floatToUint64(f float64) (uint64, error) {
+	switch {
+	case math.IsNaN(f):
+		return 0, &conversionError{value: f, target: "uint64", reason: "not a number"}
+	case f < 0:
+		return 0, &conversionError{value: f, target: "uint64", reason: "negative", clamped: uint64(0)}
+	case f >= math.MaxUint64:
+		return 0, &conversionError{value: f, target: "uint64", reason: "out of range", clamped: uint64(math.MaxUint64)}
+	case f != math.Trunc(f):
+		return 0, &conversionError{value: f, target: "uint64", reason: "fractional part", clamped: uint64(f)}
+	}
+
+	return uint64(f), nil
+}
+
+func parseUint64(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return v, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &conversionError{value: s, target: "uint64", reason: "invalid"}
+	}
+
+	return floatToUint64(f)
+}
+
+// toInt64 casts the integers, the floats and the numeric strings
+// without fractional part to int64.
+func toInt64(i interface{}) (int64, error) {
+	switch i := i.(type) {
+	case int:
+		return int64(i), nil
+	case int8:
+		return int64(i), nil
+	case int16:
+		return int64(i), nil
+	case int32:
+		return int64(i), nil
+	case int64:
+		return i, nil
+	case uint:
+		return toInt64(uint64(i))
+	case uint8:
+		return int64(i), nil
+	case uint16:
+		return int64(i), nil
+	case uint32:
+		return int64(i), nil
+	case uint64:
+		if i > math.MaxInt64 {
+			return 0, &conversionError{value: i, target: "int64", reason: "out of range", clamped: int64(math.MaxInt64)}
+		}
+
+		return int64(i), nil
+	case float32:
+		return floatToInt64(float64(i))
+	case float64:
+		return floatToInt64(i)
+	case string:
+		return parseInt64(i)
+	case []byte:
+		return parseInt64(string(i))
+	}
+
+	return 0, fmt.Errorf("could not cast %T to int64: %v", i, i)
+}
+
+func floatToInt64(f float64) (int64, error) {
+	switch {
+	case math.IsNaN(f):
+		return 0, &conversionError{value: f, target: "int64", reason: "not a number"}
+	case f < math.MinInt64:
+		return 0, &conversionError{value: f, target: "int64", reason: "out of range", clamped: int64(math.MinInt64)}
+	case f >= math.MaxInt64:
+		return 0, &conversionError{value: f, target: "int64", reason: "out of range", clamped: int64(math.MaxInt64)}
+	case f != math.Trunc(f):
+		return 0, &conversionError{value: f, target: "int64", reason: "fractional part", clamped: int64(f)}
+	}
+
+	return int64(f), nil
+}
+
+func parseInt64(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &conversionError{value: s, target: "int64", reason: "invalid"}
+	}
+
+	return floatToInt64(f)
+}
+
+// toNumber keeps the integers and the floats, the numeric strings
+// are parsed to int64, uint64 or float64.
+func toNumber(i interface{}) (interface{}, error) {
+	switch i := i.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float64:
+		return i, nil
+	case float32:
+		return float32To64(i), nil
+	case string:
+		return parseNumber(i)
+	case []byte:
+		return parseNumber(string(i))
+	}
+
+	return nil, fmt.Errorf("could not cast %T to number: %v", i, i)
+}
+
+func parseNumber(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+
+	return nil, &conversionError{value: s, target: "number", reason: "invalid"}
+}
+
+// toDouble casts the numbers and the numeric strings to float64.
+func toDouble(i interface{}) (float64, error) {
+	switch i := i.(type) {
+	case float32:
+		return float32To64(i), nil
+	case float64:
+		return i, nil
+	case uint64:
+		return float64(i), nil
+	case string:
+		return parseDouble(i)
+	case []byte:
+		return parseDouble(string(i))
+	}
+
+	v, err := toInt64(i)
+	if err != nil {
+		return 0, fmt.Errorf("could not cast %T to double: %v", i, i)
+	}
+
+	return float64(v), nil
+}
+
+func parseDouble(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, &conversionError{value: s, target: "double", reason: "invalid"}
+	}
+
+	return v, nil
+}
+
+// float32To64 keeps the shortest decimal representation of FLOAT value,
+// e.g. 0.1 is not turned into 0.10000000149011612.
+func float32To64(f float32) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+
+	return v
+}
+
+// toBool casts non-zero numbers to true, the strings may also be "true" or "false".
+func toBool(i interface{}) (bool, error) {
+	switch i := i.(type) {
+	case bool:
+		return i, nil
+	case string:
+		return parseBool(i)
+	case []byte:
+		return parseBool(string(i))
+	}
+
+	f, err := toDouble(i)
+	if err != nil {
+		return false, fmt.Errorf("could not cast %T to boolean: %v", i, i)
+	}
+
+	return f != 0, nil
+}
+
+func parseBool(s string) (bool, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return false, &conversionError{value: s, target: "boolean", reason: "invalid"}
+	}
+
+	return f != 0, nil
+}
+
+// toString formats the numbers in decimal notation.
+func toString(i interface{}) (string, error) {
+	switch i := i.(type) {
+	case string:
+		return i, nil
+	case []byte:
+		return string(i), nil
+	case float32:
+		return strconv.FormatFloat(float64(i), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(i, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(i), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", i), nil
+	}
+
+	return "", fmt.Errorf("could not cast %T to string: %v", i, i)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_attribute_fetchValue(t *testing.T) {
@@ -15,6 +16,7 @@ func Test_attribute_fetchValue(t *testing.T) {
 		cType    castType
 		onNull   interface{}
 		unsigned bool
+		dropped  bool
 	}
 	type args struct {
 		row []interface{}
@@ -188,6 +190,41 @@ func Test_attribute_fetchValue(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "DroppedColumn",
+			fields: fields{
+				colIndex: 5,
+				tupIndex: 1,
+				name:     "name",
+				vType:    typeString,
+				cType:    castNone,
+				unsigned: false,
+				dropped:  true,
+			},
+			args: args{
+				row: []interface{}{1},
+			},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "DroppedColumnOnNull",
+			fields: fields{
+				colIndex: 5,
+				tupIndex: 1,
+				name:     "name",
+				vType:    typeString,
+				cType:    castNone,
+				onNull:   "unknown",
+				unsigned: false,
+				dropped:  true,
+			},
+			args: args{
+				row: []interface{}{1},
+			},
+			want:    "unknown",
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -201,6 +238,7 @@ func Test_attribute_fetchValue(t *testing.T) {
 				cType:    tt.fields.cType,
 				onNull:   tt.fields.onNull,
 				unsigned: tt.fields.unsigned,
+				dropped:  tt.fields.dropped,
 			}
 			got, err := a.fetchValue(tt.args.row)
 			if tt.wantErr {
@@ -303,3 +341,128 @@ func Test_toUint64(t *testing.T) {
 		})
 	}
 }
+
+func Test_castTypeFromString(t *testing.T) {
+	for _, cType := range []castType{
+		castUnsigned, castInteger, castNumber, castDouble, castBoolean, castString,
+		castUnixTimestamp, castUnixTimestampMs, castISO8601, castDatetime,
+		castJSON, castDecimal, castDecimalString, castUUID, castArray, castMap,
+	} {
+		got, err := castTypeFromString(cType.String())
+		assert.NoError(t, err)
+		assert.Equal(t, cType, got)
+	}
+
+	got, err := castTypeFromString("")
+	assert.NoError(t, err)
+	assert.Equal(t, castNone, got)
+
+	_, err = castTypeFromString("varchar")
+	assert.Error(t, err)
+}
+
+func Test_attribute_fetchValue_Cast(t *testing.T) {
+	tests := []struct {
+		name    string
+		vType   attrType
+		cType   castType
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "Unsigned_DecimalString", vType: typeDecimal, cType: castUnsigned, value: "12.00", want: uint64(12)},
+		{name: "Unsigned_Fraction", vType: typeDecimal, cType: castUnsigned, value: "12.50", wantErr: true},
+		{name: "Integer_Int8", vType: typeNumber, cType: castInteger, value: int8(-5), want: int64(-5)},
+		{name: "Integer_Uint64", vType: typeNumber, cType: castInteger, value: uint64(1) << 63, wantErr: true},
+		{name: "Integer_Float", vType: typeFloat, cType: castInteger, value: float64(42), want: int64(42)},
+		{name: "Integer_FloatFraction", vType: typeFloat, cType: castInteger, value: 42.5, wantErr: true},
+		{name: "Integer_FloatOverflow", vType: typeFloat, cType: castInteger, value: 1e20, wantErr: true},
+		{name: "Integer_String", vType: typeString, cType: castInteger, value: " 17 ", want: int64(17)},
+		{name: "Integer_NotNumber", vType: typeString, cType: castInteger, value: "seventeen", wantErr: true},
+		{name: "Number_Int", vType: typeNumber, cType: castNumber, value: int32(7), want: int32(7)},
+		{name: "Number_Float32", vType: typeFloat, cType: castNumber, value: float32(0.1), want: 0.1},
+		{name: "Number_DecimalString", vType: typeDecimal, cType: castNumber, value: "12.50", want: 12.5},
+		{name: "Number_IntString", vType: typeDecimal, cType: castNumber, value: "-12", want: int64(-12)},
+		{name: "Number_Bytes", vType: typeDecimal, cType: castNumber, value: []byte("1e3"), want: float64(1000)},
+		{name: "Double_Int", vType: typeNumber, cType: castDouble, value: int64(3), want: float64(3)},
+		{name: "Double_Uint64", vType: typeNumber, cType: castDouble, value: uint64(3), want: float64(3)},
+		{name: "Double_String", vType: typeDecimal, cType: castDouble, value: "3.25", want: 3.25},
+		{name: "Double_NotNumber", vType: typeString, cType: castDouble, value: "pi", wantErr: true},
+		{name: "Boolean_TinyInt", vType: typeNumber, cType: castBoolean, value: int8(1), want: true},
+		{name: "Boolean_Zero", vType: typeNumber, cType: castBoolean, value: int64(0), want: false},
+		{name: "Boolean_Bit", vType: typeBit, cType: castBoolean, value: int64(1), want: true},
+		{name: "Boolean_String", vType: typeString, cType: castBoolean, value: "TRUE", want: true},
+		{name: "Boolean_NumericString", vType: typeString, cType: castBoolean, value: "0", want: false},
+		{name: "Boolean_Invalid", vType: typeString, cType: castBoolean, value: "yes", wantErr: true},
+		{name: "String_Int", vType: typeNumber, cType: castString, value: int64(-42), want: "-42"},
+		{name: "String_Uint", vType: typeNumber, cType: castString, value: uint64(42), want: "42"},
+		{name: "String_Float", vType: typeFloat, cType: castString, value: 0.5, want: "0.5"},
+		{name: "String_Float32", vType: typeFloat, cType: castString, value: float32(0.1), want: "0.1"},
+		{name: "String_Bytes", vType: typeBinary, cType: castString, value: []byte("raw"), want: "raw"},
+		{name: "String_Datetime", vType: typeDatetime, cType: castString, value: "2020-11-05 10:21:48", want: "2020-11-05 10:21:48"},
+		{name: "UnixTimestamp_Datetime", vType: typeDatetime, cType: castUnixTimestamp, value: "2020-11-05 10:21:48", want: int64(1604571708)},
+		{name: "UnixTimestamp_Fraction", vType: typeTimestamp, cType: castUnixTimestamp, value: "2020-11-05 10:21:48.123456", want: int64(1604571708)},
+		{name: "UnixTimestamp_Date", vType: typeDate, cType: castUnixTimestamp, value: "2020-11-05", want: int64(1604534400)},
+		{name: "UnixTimestamp_ZeroDate", vType: typeDatetime, cType: castUnixTimestamp, value: "0000-00-00 00:00:00", want: nil},
+		{name: "UnixTimestamp_Invalid", vType: typeDatetime, cType: castUnixTimestamp, value: "yesterday", wantErr: true},
+		{name: "Null", vType: typeNumber, cType: castInteger, value: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{
+				name:  "value",
+				vType: tt.vType,
+			}
+			require.NoError(t, a.castTo(tt.cType))
+
+			got, err := a.fetchValue([]interface{}{tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_attribute_castTo(t *testing.T) {
+	tests := []struct {
+		vType attrType
+		cType castType
+		want  bool
+	}{
+		{vType: typeJSON, cType: castString, want: true},
+		{vType: typeDecimal, cType: castInteger, want: true},
+		{vType: typeString, cType: castNumber, want: true},
+		{vType: typeDatetime, cType: castUnixTimestamp, want: true},
+		{vType: typeDatetime, cType: castInteger, want: false},
+		{vType: typeEnum, cType: castUnsigned, want: true},
+		{vType: typeEnum, cType: castBoolean, want: false},
+		{vType: typePoint, cType: castMap, want: true},
+		{vType: typeSet, cType: castArray, want: true},
+		{vType: typeJSON, cType: castArray, want: false},
+		{vType: typeJSON, cType: castBoolean, want: false},
+		{vType: typeTime, cType: castUnixTimestamp, want: true},
+		{vType: typeTimestamp, cType: castUnixTimestampMs, want: true},
+		{vType: typeDate, cType: castISO8601, want: true},
+		{vType: typeTimestamp, cType: castDatetime, want: true},
+		{vType: typeTime, cType: castISO8601, want: false},
+		{vType: typeTime, cType: castDatetime, want: false},
+		{vType: typeNumber, cType: castUnixTimestamp, want: false},
+	}
+
+	for _, tt := range tests {
+		a := &attribute{name: "value", vType: tt.vType}
+		err := a.castTo(tt.cType)
+		if tt.want {
+			assert.NoError(t, err, "%d to %s", tt.vType, tt.cType)
+			assert.Equal(t, tt.cType, a.cType)
+		} else {
+			assert.Error(t, err, "%d to %s", tt.vType, tt.cType)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/metrics"
+)
+
+const (
+	ddlTruncate = "truncate"
+	ddlDrop     = "drop"
+	ddlRename   = "rename"
+	ddlRenameTo = "rename_to" // the table is renamed to the mapped one
+)
+
+type truncatePolicy int
+
+const (
+	truncateSpace  truncatePolicy = iota // truncate the space
+	truncateIgnore                       // keep the tuples
+	truncateFail                         // stop the replication
+)
+
+func truncatePolicyFromString(str string) (truncatePolicy, error) {
+	switch str {
+	case "", "truncate":
+		return truncateSpace, nil
+	case "ignore":
+		return truncateIgnore, nil
+	case "fail":
+		return truncateFail, nil
+	default:
+		return truncateSpace, fmt.Errorf("unknown truncate policy: %s", str)
+	}
+}
+
+type dropPolicy int
+
+const (
+	dropPause dropPolicy = iota // skip the changes until the table is created again
+	dropFail                    // stop the replication
+)
+
+func dropPolicyFromString(str string) (dropPolicy, error) {
+	switch str {
+	case "", "pause":
+		return dropPause, nil
+	case "fail":
+		return dropFail, nil
+	default:
+		return dropPause, fmt.Errorf("unknown drop policy: %s", str)
+	}
+}
+
+// spaceTruncate is sent to sync to truncate the space
+// after the preceding changes are applied.
+type spaceTruncate struct {
+	space string
+}
+
+// tableDDL is the DDL statement of one table.
+type tableDDL struct {
+	stmt   string
+	schema string
+	table  string
+}
+
+// parseDDL returns the statements of the query changing the tables:
+// TRUNCATE, DROP and RENAME. Other statements are ignored.
+func parseDDL(p *parser.Parser, query, schema string) ([]tableDDL, error) {
+	stmts, _, err := p.Parse(query, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var ddls []tableDDL
+	add := func(stmt string, name *ast.TableName) {
+		db := name.Schema.String()
+		if db == "" {
+			db = schema
+		}
+
+		ddls = append(ddls, tableDDL{
+			stmt:   stmt,
+			schema: db,
+			table:  name.Name.String(),
+		})
+	}
+
+	for _, stmt := range stmts {
+		switch t := stmt.(type) {
+		case *ast.TruncateTableStmt:
+			add(ddlTruncate, t.Table)
+		case *ast.DropTableStmt:
+			if t.IsView {
+				continue
+			}
+
+			for _, name := range t.Tables {
+				add(ddlDrop, name)
+			}
+		case *ast.RenameTableStmt:
+			for _, tt := range t.TableToTables {
+				add(ddlRename, tt.OldTable)
+				add(ddlRenameTo, tt.NewTable)
+			}
+		}
+	}
+
+	return ddls, nil
+}
+
+// applyDDL applies the policies of the table mappings to the statement.
+func (b *Bridge) applyDDL(ddl tableDDL) error {
+	key := ruleKey(ddl.schema, ddl.table)
+
+	b.rulesMu.RLock()
+	rules, ok := b.rules[key]
+	b.rulesMu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	switch ddl.stmt {
+	case ddlTruncate:
+		// The replication stops before any space of the table is truncated.
+		for _, r := range rules {
+			switch {
+			case r.onTruncate == truncateFail:
+				b.logDDL(ddl, r, "fail")
+
+				return fmt.Errorf("mapped table %s.%s is truncated, space: %s", ddl.schema, ddl.table, r.space)
+			case r.onTruncate == truncateSpace && b.sharedSpace(r.space):
+				// Truncating the space would remove the tuples of other tables.
+				b.logDDL(ddl, r, "fail")
+
+				return fmt.Errorf("mapped table %s.%s is truncated, could not truncate space %s: %w",
+					ddl.schema, ddl.table, r.space, ErrSharedSpace)
+			}
+		}
+
+		for _, r := range rules {
+			if r.onTruncate == truncateIgnore {
+				b.logDDL(ddl, r, "ignore")
+
+				continue
+			}
+
+			b.syncCh <- &spaceTruncate{
+				space: r.space,
+			}
+			b.logDDL(ddl, r, "truncate")
+		}
+	case ddlDrop, ddlRename:
+		paused := make([]*rule, 0, len(rules))
+		for _, r := range rules {
+			if r.onDrop == dropFail {
+				b.logDDL(ddl, r, "fail")
+
+				return fmt.Errorf("mapped table %s.%s is dropped or renamed, space: %s", ddl.schema, ddl.table, r.space)
+			}
+
+			pr := *r
+			pr.paused = true
+			paused = append(paused, &pr)
+
+			b.logDDL(ddl, r, "pause")
+		}
+
+		b.rulesMu.Lock()
+		b.rules[key] = paused
+		b.rulesMu.Unlock()
+	case ddlRenameTo:
+		// Canal reports only the old name of the renamed table,
+		// so the mapping of the new name is rebuilt here.
+		if err := b.updateRule(ddl.schema, ddl.table); err != nil {
+			return err
+		}
+
+		for _, r := range rules {
+			b.logDDL(ddl, r, "rebuild")
+		}
+	}
+
+	return nil
+}
+
+func (b *Bridge) logDDL(ddl tableDDL, r *rule, action string) {
+	b.logger.Warn().
+		Str("schema", ddl.schema).
+		Str("table", ddl.table).
+		Str("space", r.space).
+		Str("statement", ddl.stmt).
+		Str("action", action).
+		Msg("DDL of the mapped table")
+
+	metrics.AddDDLAction(ruleKey(ddl.schema, ddl.table), ddl.stmt, action)
+}
@@ -0,0 +1,180 @@
+package bridge
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pingcap/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseDDL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []tableDDL
+	}{
+		{
+			name:  "Truncate",
+			query: "TRUNCATE TABLE users",
+			want: []tableDDL{
+				{stmt: ddlTruncate, schema: "city", table: "users"},
+			},
+		},
+		{
+			name:  "TruncateWithSchema",
+			query: "TRUNCATE `shop`.`orders`",
+			want: []tableDDL{
+				{stmt: ddlTruncate, schema: "shop", table: "orders"},
+			},
+		},
+		{
+			name:  "Drop",
+			query: "DROP TABLE IF EXISTS users, shop.orders",
+			want: []tableDDL{
+				{stmt: ddlDrop, schema: "city", table: "users"},
+				{stmt: ddlDrop, schema: "shop", table: "orders"},
+			},
+		},
+		{
+			name:  "DropView",
+			query: "DROP VIEW users_view",
+		},
+		{
+			name:  "Rename",
+			query: "RENAME TABLE users TO users_old, users_new TO users",
+			want: []tableDDL{
+				{stmt: ddlRename, schema: "city", table: "users"},
+				{stmt: ddlRenameTo, schema: "city", table: "users_old"},
+				{stmt: ddlRename, schema: "city", table: "users_new"},
+				{stmt: ddlRenameTo, schema: "city", table: "users"},
+			},
+		},
+		{
+			name:  "Alter",
+			query: "ALTER TABLE users ADD COLUMN age int",
+		},
+	}
+
+	p := parser.New()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDDL(p, tt.query, "city")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBridge_applyDDL_Truncate(t *testing.T) {
+	users := newTestUsersRule()
+	users.space = "users"
+
+	ignored := newTestUsersRule()
+	ignored.space = "users_by_name"
+	ignored.onTruncate = truncateIgnore
+
+	b := newTestBridge(users, ignored)
+
+	err := b.applyDDL(tableDDL{stmt: ddlTruncate, schema: "city", table: "users"})
+	require.NoError(t, err)
+
+	require.Len(t, b.syncCh, 1)
+	got, ok := (<-b.syncCh).(*spaceTruncate)
+	require.True(t, ok)
+	assert.Equal(t, "users", got.space)
+
+	// Unknown tables are ignored.
+	err = b.applyDDL(tableDDL{stmt: ddlTruncate, schema: "city", table: "logins"})
+	require.NoError(t, err)
+	assert.Empty(t, b.syncCh)
+}
+
+func TestBridge_applyDDL_TruncateSharedSpace(t *testing.T) {
+	users := newTestUsersRule()
+	users.space = "users"
+
+	archive := newTestUsersRule()
+	archive.table = "users_archive"
+	archive.space = "users"
+
+	b := newTestBridge(users, archive)
+
+	err := b.applyDDL(tableDDL{stmt: ddlTruncate, schema: "city", table: "users"})
+	assert.True(t, errors.Is(err, ErrSharedSpace))
+	assert.Empty(t, b.syncCh)
+
+	users.onTruncate = truncateIgnore
+	b = newTestBridge(users, archive)
+
+	err = b.applyDDL(tableDDL{stmt: ddlTruncate, schema: "city", table: "users"})
+	require.NoError(t, err)
+	assert.Empty(t, b.syncCh)
+}
+
+func TestBridge_applyDDL_TruncateFail(t *testing.T) {
+	users := newTestUsersRule()
+	users.space = "users"
+
+	failed := newTestUsersRule()
+	failed.space = "users_by_name"
+	failed.onTruncate = truncateFail
+
+	b := newTestBridge(users, failed)
+
+	err := b.applyDDL(tableDDL{stmt: ddlTruncate, schema: "city", table: "users"})
+	assert.Error(t, err)
+	// No space is truncated.
+	assert.Empty(t, b.syncCh)
+}
+
+func TestBridge_applyDDL_Drop(t *testing.T) {
+	r := newTestUsersRule()
+	b := newTestBridge(r)
+
+	err := b.applyDDL(tableDDL{stmt: ddlDrop, schema: "city", table: "users"})
+	require.NoError(t, err)
+
+	rules := b.rules[ruleKey("city", "users")]
+	require.Len(t, rules, 1)
+	assert.True(t, rules[0].paused)
+	assert.False(t, r.paused)
+
+	r.onDrop = dropFail
+	b = newTestBridge(r)
+
+	err = b.applyDDL(tableDDL{stmt: ddlRename, schema: "city", table: "users"})
+	assert.Error(t, err)
+}
+
+func Test_truncatePolicyFromString(t *testing.T) {
+	got, err := truncatePolicyFromString("")
+	require.NoError(t, err)
+	assert.Equal(t, truncateSpace, got)
+
+	got, err = truncatePolicyFromString("ignore")
+	require.NoError(t, err)
+	assert.Equal(t, truncateIgnore, got)
+
+	got, err = truncatePolicyFromString("fail")
+	require.NoError(t, err)
+	assert.Equal(t, truncateFail, got)
+
+	_, err = truncatePolicyFromString("delete")
+	assert.Error(t, err)
+}
+
+func Test_dropPolicyFromString(t *testing.T) {
+	got, err := dropPolicyFromString("")
+	require.NoError(t, err)
+	assert.Equal(t, dropPause, got)
+
+	got, err = dropPolicyFromString("fail")
+	require.NoError(t, err)
+	assert.Equal(t, dropFail, got)
+
+	_, err = dropPolicyFromString("drop_field")
+	assert.Error(t, err)
+}
@@ -0,0 +1,234 @@
+package bridge
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// decimalExtType is MessagePack extension type of Tarantool decimal.
+const decimalExtType = 1
+
+// maxDecimalDigits is the precision of Tarantool decimal.
+const maxDecimalDigits = 38
+
+// decimalValue is Tarantool decimal, the payload is the scale followed by
+// the packed BCD digits and the sign nibble.
+type decimalValue struct {
+	text string // decimal notation, e.g. "-10.50"
+	data []byte // extension payload
+}
+
+func (d *decimalValue) ExtensionType() int8 {
+	return decimalExtType
+}
+
+func (d *decimalValue) Len() int {
+	return len(d.data)
+}
+
+func (d *decimalValue) MarshalBinaryTo(b []byte) error {
+	copy(b, d.data)
+
+	return nil
+}
+
+func (d *decimalValue) UnmarshalBinary(b []byte) error {
+	d.data = append(d.data[:0], b...)
+	d.text = ""
+
+	return nil
+}
+
+func (d *decimalValue) String() string {
+	return d.text
+}
+
+// newDecimalValue packs the decimal notation, e.g. "-10.50".
+func newDecimalValue(text string) (*decimalValue, error) {
+	digits := text
+	negative := strings.HasPrefix(digits, "-")
+	if negative {
+		digits = digits[1:]
+	}
+
+	var scale int
+	if dot := strings.IndexByte(digits, '.'); dot != -1 {
+		scale = len(digits) - dot - 1
+		digits = digits[:dot] + digits[dot+1:]
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	if len(digits) > maxDecimalDigits {
+		return nil, &conversionError{value: text, target: "decimal", reason: "more than 38 digits"}
+	}
+
+	// The digits and the sign nibble fill whole bytes.
+	if len(digits)%2 == 0 {
+		digits = "0" + digits
+	}
+
+	sign := byte(0x0c)
+	if negative {
+		sign = 0x0d
+	}
+
+	data := msgp.AppendUint(make([]byte, 0, 1+len(digits)/2+1), uint(scale))
+	for i := 0; i+1 < len(digits); i += 2 {
+		data = append(data, (digits[i]-'0')<<4|(digits[i+1]-'0'))
+	}
+	data = append(data, (digits[len(digits)-1]-'0')<<4|sign)
+
+	return &decimalValue{text: text, data: data}, nil
+}
+
+// unpackDecimal decodes the payload of Tarantool decimal.
+func unpackDecimal(data []byte) (decimal.Decimal, error) {
+	scale, rest, err := msgp.ReadInt64Bytes(data)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("invalid decimal scale: %w", err)
+	}
+	if len(rest) == 0 {
+		return decimal.Decimal{}, fmt.Errorf("invalid decimal: no digits")
+	}
+
+	digits := make([]byte, 0, len(rest)*2)
+	for _, b := range rest {
+		digits = append(digits, '0'+b>>4, '0'+b&0x0f)
+	}
+
+	sign := digits[len(digits)-1] - '0'
+	digits = digits[:len(digits)-1]
+	for _, d := range digits {
+		if d > '9' {
+			return decimal.Decimal{}, fmt.Errorf("invalid decimal digit: %x", d-'0')
+		}
+	}
+
+	n, ok := new(big.Int).SetString(string(digits), 10)
+	if !ok {
+		return decimal.Decimal{}, fmt.Errorf("invalid decimal digits: %s", digits)
+	}
+	if sign == 0x0b || sign == 0x0d {
+		n.Neg(n)
+	}
+
+	return decimal.NewFromBigInt(n, int32(-scale)), nil
+}
+
+// extensionDecimal returns the decimal written by the replicator or read from Tarantool.
+func extensionDecimal(v interface{}) (decimal.Decimal, bool) {
+	var data []byte
+	switch d := v.(type) {
+	case *decimalValue:
+		data = d.data
+	case *msgp.RawExtension:
+		if d.Type != decimalExtType {
+			return decimal.Decimal{}, false
+		}
+		data = d.Data
+	default:
+		return decimal.Decimal{}, false
+	}
+
+	d, err := unpackDecimal(data)
+	if err != nil {
+		return decimal.Decimal{}, false
+	}
+
+	return d, true
+}
+
+// parseDecimal converts the value to the exact decimal.
+func parseDecimal(i interface{}) (decimal.Decimal, error) {
+	switch v := i.(type) {
+	case decimal.Decimal:
+		return v, nil
+	case int:
+		return decimal.New(int64(v), 0), nil
+	case int8:
+		return decimal.New(int64(v), 0), nil
+	case int16:
+		return decimal.New(int64(v), 0), nil
+	case int32:
+		return decimal.New(int64(v), 0), nil
+	case int64:
+		return decimal.New(v, 0), nil
+	case uint, uint8, uint16, uint32, uint64:
+		return decimal.NewFromString(fmt.Sprintf("%d", v))
+	case float32:
+		return parseDecimalString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	case float64:
+		return parseDecimalString(strconv.FormatFloat(v, 'f', -1, 64))
+	case string:
+		return parseDecimalString(v)
+	case []byte:
+		return parseDecimalString(string(v))
+	}
+
+	return decimal.Decimal{}, fmt.Errorf("could not cast %T to decimal: %v", i, i)
+}
+
+func parseDecimalString(s string) (decimal.Decimal, error) {
+	d, err := decimal.NewFromString(strings.TrimSpace(s))
+	if err != nil {
+		return decimal.Decimal{}, &conversionError{value: s, target: "decimal", reason: "invalid"}
+	}
+
+	return d, nil
+}
+
+// formatDecimal returns the decimal notation of the value with at least
+// scale digits after the point, the digits of the value are never lost.
+func formatDecimal(d decimal.Decimal, scale int32) string {
+	places := scale
+	if exp := d.Exponent(); -exp > places {
+		places = -exp
+	}
+
+	return d.StringFixed(places)
+}
+
+// toDecimal casts the number to Tarantool decimal keeping the scale of the column.
+func toDecimal(i interface{}, scale int32) (interface{}, error) {
+	d, err := parseDecimal(i)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDecimalValue(formatDecimal(d, scale))
+}
+
+// toDecimalString casts the number to the exact decimal notation
+// keeping the scale of the column, e.g. "10.50".
+func toDecimalString(i interface{}, scale int32) (interface{}, error) {
+	d, err := parseDecimal(i)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatDecimal(d, scale), nil
+}
+
+var decimalScaleRegex = regexp.MustCompile(`^decimal\(\d+,\s*(\d+)\)`)
+
+// decimalScale returns the number of digits after the point of DECIMAL column type,
+// e.g. 2 for "decimal(10,2)".
+func decimalScale(rawType string) int32 {
+	m := decimalScaleRegex.FindStringSubmatch(strings.ToLower(rawType))
+	if m == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(m[1])
+
+	return int32(n)
+}
@@ -0,0 +1,134 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func Test_newDecimalValue(t *testing.T) {
+	tests := []struct {
+		text string
+		want []byte
+	}{
+		{text: "-12.34", want: []byte{0xd6, 0x01, 0x02, 0x01, 0x23, 0x4d}},
+		{text: "12.340", want: []byte{0xd6, 0x01, 0x03, 0x12, 0x34, 0x0c}},
+		{text: "0", want: []byte{0xd5, 0x01, 0x00, 0x0c}},
+		{text: "0.00", want: []byte{0xd5, 0x01, 0x02, 0x0c}},
+		{text: "100", want: []byte{0xc7, 0x03, 0x01, 0x00, 0x10, 0x0c}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.text, func(t *testing.T) {
+			v, err := newDecimalValue(tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.text, v.String())
+
+			got, err := msgp.AppendIntf(nil, v)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// Tarantool returns the decimal as the raw extension.
+			raw, _, err := msgp.ReadIntfBytes(got)
+			require.NoError(t, err)
+			assert.True(t, equalTuples([]interface{}{v}, []interface{}{raw}))
+		})
+	}
+
+	_, err := newDecimalValue("1" + strings.Repeat("0", maxDecimalDigits))
+	assert.Error(t, err)
+}
+
+func Test_attribute_fetchValue_Decimal(t *testing.T) {
+	tests := []struct {
+		name    string
+		vType   attrType
+		cType   castType
+		scale   int32
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "Binlog", vType: typeDecimal, cType: castDecimalString, scale: 2, value: decimal.New(105, -1), want: "10.50"},
+		{name: "Dump", vType: typeDecimal, cType: castDecimalString, scale: 2, value: "-0.01", want: "-0.01"},
+		{name: "Precise", vType: typeDecimal, cType: castDecimalString, scale: 2, value: "12345678901234567890.12", want: "12345678901234567890.12"},
+		{name: "MoreDigitsThanScale", vType: typeDecimal, cType: castDecimalString, value: 0.125, want: "0.125"},
+		{name: "Integer", vType: typeNumber, cType: castDecimalString, value: uint64(18446744073709551615), want: "18446744073709551615"},
+		{name: "Invalid", vType: typeString, cType: castDecimalString, value: "ten", wantErr: true},
+		{name: "Extension", vType: typeDecimal, cType: castDecimal, scale: 2, value: decimal.New(-1234, -2), want: &decimalValue{text: "-12.34", data: []byte{0x02, 0x01, 0x23, 0x4d}}},
+		{name: "TooManyDigits", vType: typeDecimal, cType: castDecimal, value: strings.Repeat("9", 39), wantErr: true},
+		{name: "String", vType: typeDecimal, cType: castString, value: decimal.New(1, -1), want: "0.1"},
+		{name: "Number", vType: typeDecimal, cType: castNumber, value: decimal.New(25, -1), want: 2.5},
+		{name: "NoCast", vType: typeDecimal, value: decimal.New(25, -1), want: 2.5},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{vType: tt.vType, cType: tt.cType, scale: tt.scale}
+
+			got, err := a.fetchValue([]interface{}{tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_unpackDecimal(t *testing.T) {
+	for _, text := range []string{"-12.34", "12.340", "0", "0.00", "100", "12345678901234567890.123456789012345678"} {
+		v, err := newDecimalValue(text)
+		require.NoError(t, err)
+
+		got, err := unpackDecimal(v.data)
+		require.NoError(t, err)
+		assert.Equal(t, text, got.StringFixed(-got.Exponent()), text)
+	}
+
+	// The scale may be negative.
+	got, err := unpackDecimal([]byte{0xff, 0x1c})
+	require.NoError(t, err)
+	assert.Equal(t, "10", got.String())
+
+	_, err = unpackDecimal([]byte{0x01})
+	assert.Error(t, err)
+
+	_, err = unpackDecimal([]byte{0x00, 0xa1, 0x0c})
+	assert.Error(t, err)
+}
+
+func Test_attribute_sourceValue_Decimal(t *testing.T) {
+	a := &attribute{vType: typeDecimal, cType: castDecimal, scale: 2}
+
+	key, err := a.fetchValue([]interface{}{"-12345678901234567890.10"})
+	require.NoError(t, err)
+	assert.Equal(t, "-12345678901234567890.10", a.sourceValue(key))
+
+	// Tarantool returns the decimal as the raw extension.
+	raw := &msgp.RawExtension{Type: decimalExtType, Data: []byte{0x01, 0x10, 0x5c}}
+	assert.Equal(t, "10.50", a.sourceValue(raw))
+}
+
+func Test_decimalScale(t *testing.T) {
+	assert.Equal(t, int32(2), decimalScale("decimal(10,2)"))
+	assert.Equal(t, int32(4), decimalScale("DECIMAL(20, 4) unsigned"))
+	assert.Equal(t, int32(0), decimalScale("decimal(10)"))
+	assert.Equal(t, int32(0), decimalScale("int(11)"))
+}
+
+func Test_chunkKey(t *testing.T) {
+	table := newTestOrdersTable()
+	table.PKColumns = []int{0, 2}
+
+	row := []interface{}{uint64(1), "eu", decimal.New(1050, -2), int64(1), nil}
+	assert.Equal(t, []interface{}{uint64(1), "10.5"}, chunkKey(table, row))
+}
@@ -0,0 +1,259 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// DumpProgress describes the progress of the initial dump.
+type DumpProgress struct {
+	Tables     int
+	TablesDone int
+	Rows       uint64
+}
+
+// dumpChunk is sent to sync right after the chunk rows,
+// so the progress is saved only when the rows are applied.
+type dumpChunk struct {
+	table  string // rule key
+	lastPK []interface{}
+	rows   int
+	done   bool
+}
+
+// dumpDone is sent to sync after the dump position,
+// the saved progress is not needed anymore.
+type dumpDone struct{}
+
+// dumpResumed is sent to sync after the rows of the resumed dump. The rows are
+// read without the consistent snapshot, so they may contain the inserts the binlog
+// replays until the position the reads are finished at.
+type dumpResumed struct {
+	until position
+}
+
+type tableDump struct {
+	LastPK []interface{}
+	Rows   uint64
+	Done   bool
+}
+
+// dumpState is the progress of the initial dump: the position
+// the dump is consistent with and the last copied primary key of each table.
+type dumpState struct {
+	pos    position
+	tables map[string]*tableDump
+
+	mu *sync.RWMutex
+}
+
+func newDumpState(pos position) *dumpState {
+	return &dumpState{
+		pos:    pos,
+		tables: make(map[string]*tableDump),
+		mu:     &sync.RWMutex{},
+	}
+}
+
+func (d *dumpState) position() position {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.pos
+}
+
+// restore replaces the progress by the loaded one.
+func (d *dumpState) restore(from *dumpState) {
+	from.mu.RLock()
+	defer from.mu.RUnlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pos = from.pos
+	d.tables = make(map[string]*tableDump, len(from.tables))
+	for key, t := range from.tables {
+		cp := *t
+		d.tables[key] = &cp
+	}
+}
+
+// table returns a copy of the table progress.
+func (d *dumpState) table(key string) tableDump {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if t, ok := d.tables[key]; ok {
+		return *t
+	}
+
+	return tableDump{}
+}
+
+// update applies the chunk and returns the new table progress.
+func (d *dumpState) update(c *dumpChunk) tableDump {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, ok := d.tables[c.table]
+	if !ok {
+		t = &tableDump{}
+		d.tables[c.table] = t
+	}
+
+	t.Rows += uint64(c.rows)
+	if c.lastPK != nil {
+		t.LastPK = c.lastPK
+	}
+	t.Done = c.done
+
+	return *t
+}
+
+func (d *dumpState) progress(tables int) DumpProgress {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	p := DumpProgress{
+		Tables: tables,
+	}
+	for _, t := range d.tables {
+		p.Rows += t.Rows
+		if t.Done {
+			p.TablesDone++
+		}
+	}
+
+	return p
+}
+
+func (d *dumpState) MarshalJSON() ([]byte, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	tables := make(map[string]*savedTableDump, len(d.tables))
+	for key, t := range d.tables {
+		saved := &savedTableDump{Rows: t.Rows, Done: t.Done}
+		for _, v := range t.LastPK {
+			pk, err := encodePKValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", key, err)
+			}
+
+			saved.LastPK = append(saved.LastPK, pk)
+		}
+
+		tables[key] = saved
+	}
+
+	return json.Marshal(&struct {
+		Pos    position                   `json:"pos"`
+		Tables map[string]*savedTableDump `json:"tables"`
+	}{
+		Pos:    d.pos,
+		Tables: tables,
+	})
+}
+
+// unmarshalDumpState decodes the progress saved by MarshalJSON,
+// pos must be a decodable position of the current replication mode.
+func unmarshalDumpState(b []byte, pos position) (*dumpState, error) {
+	s := struct {
+		Pos    json.RawMessage            `json:"pos"`
+		Tables map[string]*savedTableDump `json:"tables"`
+	}{}
+
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(s.Pos, &pos); err != nil {
+		return nil, err
+	}
+
+	state := newDumpState(pos)
+	for key, saved := range s.Tables {
+		if saved == nil {
+			continue
+		}
+
+		t := &tableDump{Rows: saved.Rows, Done: saved.Done}
+		for _, v := range saved.LastPK {
+			pk, err := decodePKValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", key, err)
+			}
+
+			t.LastPK = append(t.LastPK, pk)
+		}
+
+		state.tables[key] = t
+	}
+
+	return state, nil
+}
+
+// savedTableDump is the saved table progress, the primary key values
+// are saved with their types.
+type savedTableDump struct {
+	LastPK []pkValue `json:"last_pk,omitempty"`
+	Rows   uint64    `json:"rows"`
+	Done   bool      `json:"done"`
+}
+
+// pkValue is the typed primary key value, integers are saved as strings
+// to be passed to MySQL exactly and the strings are saved in base64
+// to keep the bytes of BINARY and VARBINARY values.
+type pkValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+const (
+	pkInt   = "int"
+	pkUint  = "uint"
+	pkFloat = "float"
+	pkBytes = "bytes"
+)
+
+func encodePKValue(v interface{}) (pkValue, error) {
+	switch pk := v.(type) {
+	case int64:
+		return pkValue{Type: pkInt, Value: strconv.FormatInt(pk, 10)}, nil
+	case uint64:
+		return pkValue{Type: pkUint, Value: strconv.FormatUint(pk, 10)}, nil
+	case float64:
+		return pkValue{Type: pkFloat, Value: strconv.FormatFloat(pk, 'g', -1, 64)}, nil
+	case string:
+		return pkValue{Type: pkBytes, Value: base64.StdEncoding.EncodeToString([]byte(pk))}, nil
+	case []byte:
+		return pkValue{Type: pkBytes, Value: base64.StdEncoding.EncodeToString(pk)}, nil
+	}
+
+	return pkValue{}, fmt.Errorf("unexpected primary key value type %T", v)
+}
+
+// decodePKValue restores the primary key value saved by encodePKValue,
+// the bytes are restored as string the same as the dump reads them.
+func decodePKValue(v pkValue) (interface{}, error) {
+	switch v.Type {
+	case pkInt:
+		return strconv.ParseInt(v.Value, 10, 64)
+	case pkUint:
+		return strconv.ParseUint(v.Value, 10, 64)
+	case pkFloat:
+		return strconv.ParseFloat(v.Value, 64)
+	case pkBytes:
+		b, err := base64.StdEncoding.DecodeString(v.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return string(b), nil
+	}
+
+	return nil, fmt.Errorf("unexpected primary key value type %q", v.Type)
+}
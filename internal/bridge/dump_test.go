@@ -0,0 +1,98 @@
+package bridge
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpState_Progress(t *testing.T) {
+	state := newDumpState(nil)
+
+	state.update(&dumpChunk{table: "city:users", lastPK: []interface{}{int64(2)}, rows: 2})
+	state.update(&dumpChunk{table: "city:users", lastPK: []interface{}{int64(3)}, rows: 1, done: true})
+	state.update(&dumpChunk{table: "city:logins", lastPK: []interface{}{int64(1), "10.20.10.1"}, rows: 1})
+
+	assert.Equal(t, tableDump{LastPK: []interface{}{int64(3)}, Rows: 3, Done: true}, state.table("city:users"))
+	assert.Equal(t, tableDump{}, state.table("city:unknown"))
+	assert.Equal(t, DumpProgress{Tables: 3, TablesDone: 1, Rows: 4}, state.progress(3))
+}
+
+func TestDumpState_Marshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		pos      position
+		gtidMode bool
+		lastPK   []interface{}
+	}{
+		{
+			name:     "GTID",
+			pos:      newGTIDSet(mustCreateGTID(mysql.MySQLFlavor, "07812e7f-5dad-11e6-b5b3-525400d2e382:1-939564")),
+			gtidMode: true,
+			lastPK:   []interface{}{int64(-10), "10.20.10.1"},
+		},
+		{
+			name: "Binlog",
+			pos: newBinlogPos(mysql.Position{
+				Name: "mysql-bin.001650",
+				Pos:  394877672,
+			}),
+			gtidMode: false,
+			lastPK:   []interface{}{uint64(18446744073709551615)},
+		},
+		{
+			name:     "Binary",
+			pos:      newBinlogPos(mysql.Position{Name: "mysql-bin.001650", Pos: 4}),
+			gtidMode: false,
+			lastPK:   []interface{}{string([]byte{0xff, 0x00, 0xc3, 0x28}), 1.5},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			state := newDumpState(tt.pos)
+			state.update(&dumpChunk{table: "city:users", lastPK: tt.lastPK, rows: 10})
+
+			buf, err := json.Marshal(state)
+			require.NoError(t, err)
+
+			got, err := unmarshalDumpState(buf, decodablePosition(tt.gtidMode, mysql.MySQLFlavor))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.pos, got.position())
+			assert.Equal(t, state.table("city:users"), got.table("city:users"))
+		})
+	}
+}
+
+func Test_decodePKValue(t *testing.T) {
+	tests := []struct {
+		arg     pkValue
+		want    interface{}
+		wantErr bool
+	}{
+		{arg: pkValue{Type: "int", Value: "-5"}, want: int64(-5)},
+		{arg: pkValue{Type: "uint", Value: "18446744073709551615"}, want: uint64(18446744073709551615)},
+		{arg: pkValue{Type: "float", Value: "1.5"}, want: 1.5},
+		{arg: pkValue{Type: "bytes", Value: "Ym9i"}, want: "bob"},
+		{arg: pkValue{Type: "bytes", Value: "/wDDKA=="}, want: "\xff\x00\xc3\x28"},
+		{arg: pkValue{Type: "bytes", Value: "?"}, wantErr: true},
+		{arg: pkValue{Type: "bool", Value: "true"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := decodePKValue(tt.arg)
+		if tt.wantErr {
+			assert.Error(t, err)
+
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
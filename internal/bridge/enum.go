@@ -0,0 +1,142 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// enumIndex returns the number of ENUM value and its label. The binlog holds
+// the numbers, the dump holds the labels. The empty invalid value is number 0.
+func enumIndex(i interface{}, labels []string) (int64, string, error) {
+	switch v := i.(type) {
+	case string:
+		return enumLabelIndex(v, labels)
+	case []byte:
+		return enumLabelIndex(string(v), labels)
+	}
+
+	n, err := toInt64(i)
+	if err != nil {
+		return 0, "", fmt.Errorf("could not cast %T to enum: %v", i, i)
+	}
+	if n == 0 {
+		return 0, "", nil
+	}
+	if n < 0 || n > int64(len(labels)) {
+		return 0, "", &conversionError{value: n, target: "enum", reason: "out of range"}
+	}
+
+	return n, labels[n-1], nil
+}
+
+func enumLabelIndex(label string, labels []string) (int64, string, error) {
+	if label == "" {
+		return 0, "", nil
+	}
+
+	for i, l := range labels {
+		if l == label {
+			return int64(i) + 1, label, nil
+		}
+	}
+
+	return 0, "", &conversionError{value: label, target: "enum", reason: "unknown label"}
+}
+
+// setMembers returns the bitmask of SET value and its labels in the order of the column
+// definition. The binlog holds the bitmasks, the dump holds the comma-separated labels.
+func setMembers(i interface{}, labels []string) (uint64, []string, error) {
+	switch v := i.(type) {
+	case string:
+		return setLabelsMask(v, labels)
+	case []byte:
+		return setLabelsMask(string(v), labels)
+	}
+
+	n, err := toInt64(i)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not cast %T to set: %v", i, i)
+	}
+
+	mask := uint64(n)
+	if len(labels) < 64 && mask>>uint(len(labels)) != 0 {
+		return 0, nil, &conversionError{value: n, target: "set", reason: "out of range"}
+	}
+
+	return mask, setMaskMembers(mask, labels), nil
+}
+
+func setLabelsMask(s string, labels []string) (uint64, []string, error) {
+	var mask uint64
+	if s != "" {
+		for _, member := range strings.Split(s, ",") {
+			found := false
+			for i, l := range labels {
+				if l == member {
+					mask |= 1 << uint(i)
+					found = true
+
+					break
+				}
+			}
+			if !found {
+				return 0, nil, &conversionError{value: s, target: "set", reason: "unknown label " + member}
+			}
+		}
+	}
+
+	return mask, setMaskMembers(mask, labels), nil
+}
+
+func setMaskMembers(mask uint64, labels []string) []string {
+	members := make([]string, 0, len(labels))
+	for i, l := range labels {
+		if mask&(1<<uint(i)) != 0 {
+			members = append(members, l)
+		}
+	}
+
+	return members
+}
+
+// castEnum represents ENUM value by its label or its number.
+func (a *attribute) castEnum(value interface{}) (interface{}, error) {
+	n, label, err := enumIndex(value, a.labels)
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.cType {
+	case castString:
+		return label, nil
+	case castUnsigned:
+		return uint64(n), nil
+	default:
+		return n, nil
+	}
+}
+
+// castSet represents SET value by the array of its labels,
+// the comma-separated labels or the bitmask.
+func (a *attribute) castSet(value interface{}) (interface{}, error) {
+	mask, members, err := setMembers(value, a.labels)
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.cType {
+	case castArray:
+		arr := make([]interface{}, 0, len(members))
+		for _, m := range members {
+			arr = append(arr, m)
+		}
+
+		return arr, nil
+	case castString:
+		return strings.Join(members, ","), nil
+	case castUnsigned:
+		return mask, nil
+	default:
+		return int64(mask), nil
+	}
+}
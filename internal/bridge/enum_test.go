@@ -0,0 +1,117 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_attribute_fetchValue_Enum(t *testing.T) {
+	labels := []string{"new", "paid", "cancelled"}
+
+	tests := []struct {
+		name    string
+		cType   castType
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "Binlog", value: int64(2), want: int64(2)},
+		{name: "Dump", value: "paid", want: int64(2)},
+		{name: "BinlogLabel", cType: castString, value: int64(3), want: "cancelled"},
+		{name: "DumpLabel", cType: castString, value: "cancelled", want: "cancelled"},
+		{name: "Unsigned", cType: castUnsigned, value: "new", want: uint64(1)},
+		{name: "Invalid", cType: castString, value: int64(0), want: ""},
+		{name: "DumpInvalid", value: "", want: int64(0)},
+		{name: "OutOfRange", value: int64(4), wantErr: true},
+		{name: "UnknownLabel", value: "refunded", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{vType: typeEnum, cType: tt.cType, labels: labels}
+
+			got, err := a.fetchValue([]interface{}{tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_attribute_fetchValue_Set(t *testing.T) {
+	labels := []string{"sms", "email", "push"}
+
+	tests := []struct {
+		name    string
+		cType   castType
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "Binlog", value: int64(5), want: int64(5)},
+		{name: "Dump", value: "sms,push", want: int64(5)},
+		{name: "BinlogArray", cType: castArray, value: int64(6), want: []interface{}{"email", "push"}},
+		{name: "DumpArray", cType: castArray, value: "push,email", want: []interface{}{"email", "push"}},
+		{name: "Empty", cType: castArray, value: "", want: []interface{}{}},
+		{name: "String", cType: castString, value: int64(3), want: "sms,email"},
+		{name: "Unsigned", cType: castUnsigned, value: "email", want: uint64(2)},
+		{name: "OutOfRange", value: int64(8), wantErr: true},
+		{name: "UnknownLabel", value: "sms,fax", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{vType: typeSet, cType: tt.cType, labels: labels}
+
+			got, err := a.fetchValue([]interface{}{tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_attribute_fetchValue_Bit(t *testing.T) {
+	tests := []struct {
+		name    string
+		cType   castType
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "Binlog", value: int64(5), want: int64(5)},
+		{name: "Dump", value: "\x01\x02", want: uint64(258)},
+		{name: "BinlogBoolean", cType: castBoolean, value: int64(1), want: true},
+		{name: "DumpBoolean", cType: castBoolean, value: "\x00", want: false},
+		{name: "DumpUnsigned", cType: castUnsigned, value: []byte{0x80, 0, 0, 0, 0, 0, 0, 0}, want: uint64(1) << 63},
+		{name: "TooLong", value: "\x01\x02\x03\x04\x05\x06\x07\x08\x09", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{vType: typeBit, cType: tt.cType}
+
+			got, err := a.fetchValue([]interface{}{tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,911 @@
+package bridge
+
+import (
+	"fmt"
+
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/parser"
+	"github.com/pingcap/parser/ast"
+
+	"github.com/pingcap/parser/opcode"
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-mysql/schema"
+)
+
+// rowFilter is the where predicate of the mapping,
+// only the rows it matches are replicated.
+//
+// The predicate is parsed as the WHERE clause of the query and is limited
+// to comparisons (=, !=, <>, <, <=, >, >=), IS [NOT] NULL, [NOT] IN (...),
+// AND, OR, NOT and parentheses. The operands are the columns of the table
+// and the literals: numbers, quoted strings, NULL, TRUE and FALSE.
+// Comparisons with NULL are unknown as in SQL, the rows the predicate
+// is unknown for are not matched.
+//
+// Strings are compared case-insensitively as with _ci collations, except
+// BINARY, VARBINARY and BLOB columns and the columns with _bin or _cs collation.
+// DECIMAL values are compared exactly.
+type rowFilter struct {
+	expr     string
+	columns  []string // names of the columns the predicate refers to
+	where    ast.ExprNode
+	operands map[ast.ExprNode]filterOperand
+
+	literals []interface{} // values of the literals in order of appearance
+	next     int           // index of the next literal to compile
+}
+
+func newRowFilter(expr string, table *schema.Table) (*rowFilter, error) {
+	bound, literals, err := bindFilterLiterals(expr)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %q: %w", expr, err)
+	}
+
+	stmt, err := parser.New().ParseOneStmt("SELECT 1 FROM t WHERE "+bound, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %q: %w", expr, err)
+	}
+
+	sel, ok := stmt.(*ast.SelectStmt)
+	if !ok || sel.Where == nil || sel.GroupBy != nil || sel.Having != nil || sel.WindowSpecs != nil ||
+		sel.OrderBy != nil || sel.Limit != nil || sel.LockTp != ast.SelectLockNone {
+		return nil, fmt.Errorf("could not parse %q: must be a single predicate", expr)
+	}
+
+	f := &rowFilter{
+		expr:     expr,
+		where:    sel.Where,
+		operands: make(map[ast.ExprNode]filterOperand),
+		literals: literals,
+	}
+	if err := f.compilePredicate(sel.Where, table); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *rowFilter) match(row []interface{}) bool {
+	return f.eval(f.where, row) == triTrue
+}
+
+// rebuild compiles the predicate against the altered table.
+// Returns the names of the dropped columns the predicate refers to,
+// the filter is left as is in that case.
+func (f *rowFilter) rebuild(table *schema.Table) (*rowFilter, []string, error) {
+	if f == nil {
+		return nil, nil, nil
+	}
+
+	var dropped []string
+	for _, name := range f.columns {
+		if table.FindColumn(name) == -1 {
+			dropped = append(dropped, name)
+		}
+	}
+	if len(dropped) > 0 {
+		return f, dropped, nil
+	}
+
+	rebuilt, err := newRowFilter(f.expr, table)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rebuilt, nil, nil
+}
+
+// compilePredicate checks the expression is supported and resolves its operands.
+func (f *rowFilter) compilePredicate(n ast.ExprNode, table *schema.Table) error {
+	switch n := n.(type) {
+	case *ast.ParenthesesExpr:
+		return f.compilePredicate(n.Expr, table)
+	case *ast.BinaryOperationExpr:
+		switch n.Op {
+		case opcode.LogicAnd, opcode.LogicOr:
+			if err := f.compilePredicate(n.L, table); err != nil {
+				return err
+			}
+
+			return f.compilePredicate(n.R, table)
+		case opcode.EQ, opcode.NE, opcode.LT, opcode.LE, opcode.GT, opcode.GE:
+			if _, err := f.compileOperand(n.L, table); err != nil {
+				return err
+			}
+			_, err := f.compileOperand(n.R, table)
+
+			return err
+		}
+	case *ast.UnaryOperationExpr:
+		if n.Op == opcode.Not {
+			return f.compilePredicate(n.V, table)
+		}
+	case *ast.IsNullExpr:
+		_, err := f.compileOperand(n.Expr, table)
+
+		return err
+	case *ast.PatternInExpr:
+		if n.Sel != nil {
+			return unsupportedFilterExpr(n)
+		}
+		if _, err := f.compileOperand(n.Expr, table); err != nil {
+			return err
+		}
+		for _, item := range n.List {
+			if _, err := f.compileOperand(item, table); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// The operand used as a predicate, e.g. "WHERE is_active".
+	_, err := f.compileOperand(n, table)
+
+	return err
+}
+
+func (f *rowFilter) compileOperand(n ast.ExprNode, table *schema.Table) (filterOperand, error) {
+	var operand filterOperand
+	switch n := n.(type) {
+	case *ast.ParenthesesExpr:
+		inner, err := f.compileOperand(n.Expr, table)
+		if err != nil {
+			return nil, err
+		}
+		operand = inner
+	case *ast.ColumnNameExpr:
+		if n.Name.Schema.O != "" || n.Name.Table.O != "" {
+			return nil, unsupportedFilterExpr(n)
+		}
+
+		column, err := f.compileColumn(n.Name.Name.O, table)
+		if err != nil {
+			return nil, err
+		}
+		operand = column
+	case ast.ParamMarkerExpr:
+		v, err := f.nextLiteral()
+		if err != nil {
+			return nil, err
+		}
+		operand = &literalOperand{v: v}
+	case *ast.UnaryOperationExpr:
+		// Only numbers may be negative.
+		if _, ok := n.V.(ast.ParamMarkerExpr); ok && n.Op == opcode.Minus {
+			v, err := f.nextLiteral()
+			if err != nil {
+				return nil, err
+			}
+			if neg, ok := negateFilterValue(v); ok {
+				operand = &literalOperand{v: neg}
+			}
+		}
+	}
+
+	if operand == nil {
+		return nil, unsupportedFilterExpr(n)
+	}
+	f.operands[n] = operand
+
+	return operand, nil
+}
+
+// nextLiteral returns the value of the next placeholder, the operands
+// are compiled in order of appearance as the literals are bound.
+func (f *rowFilter) nextLiteral() (interface{}, error) {
+	if f.next >= len(f.literals) {
+		return nil, fmt.Errorf("unexpected placeholder")
+	}
+
+	v := f.literals[f.next]
+	f.next++
+
+	return v, nil
+}
+
+func (f *rowFilter) compileColumn(name string, table *schema.Table) (*columnOperand, error) {
+	idx := table.FindColumn(name)
+	if idx == -1 {
+		return nil, fmt.Errorf("unknown column %s", name)
+	}
+
+	col := table.Columns[idx]
+	f.columns = append(f.columns, col.Name)
+
+	operand := &columnOperand{index: idx}
+	switch col.Type {
+	case schema.TYPE_ENUM:
+		operand.enum = col.EnumValues
+	case schema.TYPE_SET:
+		operand.set = col.SetValues
+	case schema.TYPE_BIT:
+		operand.bit = true
+	case schema.TYPE_BINARY:
+		operand.exact = true
+	}
+	if strings.Contains(col.RawType, "blob") ||
+		strings.HasSuffix(col.Collation, "_bin") || strings.HasSuffix(col.Collation, "_cs") {
+		operand.exact = true
+	}
+
+	return operand, nil
+}
+
+// unsupportedFilterExpr describes the expression the filter does not support,
+// the literals are not restored from the parsed expression.
+func unsupportedFilterExpr(n ast.ExprNode) error {
+	var what string
+	switch n := n.(type) {
+	case *ast.BinaryOperationExpr:
+		var sb strings.Builder
+		n.Op.Format(&sb)
+		what = fmt.Sprintf("operator %s", strings.TrimSpace(sb.String()))
+	case *ast.UnaryOperationExpr:
+		var sb strings.Builder
+		n.Op.Format(&sb)
+		what = fmt.Sprintf("unary operator %s", strings.TrimSpace(sb.String()))
+	case *ast.PatternLikeExpr:
+		what = "operator LIKE"
+	case *ast.PatternRegexpExpr:
+		what = "operator REGEXP"
+	case *ast.PatternInExpr:
+		what = "subquery"
+	case *ast.FuncCallExpr:
+		what = fmt.Sprintf("function %s", n.FnName.O)
+	case *ast.ColumnNameExpr:
+		what = fmt.Sprintf("qualified column %s", n.Name.OrigColName())
+	case ast.ValueExpr:
+		what = "literal"
+	default:
+		what = fmt.Sprintf("expression %T", n)
+	}
+
+	return fmt.Errorf("unsupported %s", what)
+}
+
+// eval evaluates the compiled expression against the row.
+func (f *rowFilter) eval(n ast.ExprNode, row []interface{}) tribool {
+	if operand, isOperand := f.operands[n]; isOperand {
+		c, ok := compareFilterValues(operand.value(row), int64(0))
+		if !ok {
+			return triUnknown
+		}
+
+		return triboolOf(c != 0)
+	}
+
+	switch n := n.(type) {
+	case *ast.ParenthesesExpr:
+		return f.eval(n.Expr, row)
+	case *ast.UnaryOperationExpr:
+		return f.eval(n.V, row).not()
+	case *ast.IsNullExpr:
+		return triboolOf((f.operands[n.Expr].value(row) == nil) != n.Not)
+	case *ast.PatternInExpr:
+		return f.evalIn(n, row)
+	case *ast.BinaryOperationExpr:
+		switch n.Op {
+		case opcode.LogicAnd:
+			return f.evalAnd(n, row)
+		case opcode.LogicOr:
+			return f.evalOr(n, row)
+		}
+
+		return f.evalCompare(n, row)
+	}
+
+	return triUnknown
+}
+
+func (f *rowFilter) evalOr(n *ast.BinaryOperationExpr, row []interface{}) tribool {
+	l := f.eval(n.L, row)
+	if l == triTrue {
+		return triTrue
+	}
+
+	r := f.eval(n.R, row)
+	switch {
+	case r == triTrue:
+		return triTrue
+	case l == triUnknown || r == triUnknown:
+		return triUnknown
+	default:
+		return triFalse
+	}
+}
+
+func (f *rowFilter) evalAnd(n *ast.BinaryOperationExpr, row []interface{}) tribool {
+	l := f.eval(n.L, row)
+	if l == triFalse {
+		return triFalse
+	}
+
+	r := f.eval(n.R, row)
+	switch {
+	case r == triFalse:
+		return triFalse
+	case l == triUnknown || r == triUnknown:
+		return triUnknown
+	default:
+		return triTrue
+	}
+}
+
+func (f *rowFilter) evalCompare(n *ast.BinaryOperationExpr, row []interface{}) tribool {
+	c, ok := compareFilterValues(f.operands[n.L].value(row), f.operands[n.R].value(row))
+	if !ok {
+		return triUnknown
+	}
+
+	switch n.Op {
+	case opcode.EQ:
+		return triboolOf(c == 0)
+	case opcode.NE:
+		return triboolOf(c != 0)
+	case opcode.LT:
+		return triboolOf(c < 0)
+	case opcode.LE:
+		return triboolOf(c <= 0)
+	case opcode.GT:
+		return triboolOf(c > 0)
+	default:
+		return triboolOf(c >= 0)
+	}
+}
+
+func (f *rowFilter) evalIn(n *ast.PatternInExpr, row []interface{}) tribool {
+	v := f.operands[n.Expr].value(row)
+	if v == nil {
+		return triUnknown
+	}
+
+	res := triFalse
+	for _, item := range n.List {
+		c, ok := compareFilterValues(v, f.operands[item].value(row))
+		if !ok {
+			res = triUnknown
+
+			continue
+		}
+		if c == 0 {
+			res = triTrue
+
+			break
+		}
+	}
+
+	if n.Not {
+		return res.not()
+	}
+
+	return res
+}
+
+// tribool is the result of SQL three-valued logic.
+type tribool int8
+
+const (
+	triFalse tribool = iota
+	triTrue
+	triUnknown
+)
+
+func (t tribool) not() tribool {
+	switch t {
+	case triTrue:
+		return triFalse
+	case triFalse:
+		return triTrue
+	default:
+		return triUnknown
+	}
+}
+
+func triboolOf(b bool) tribool {
+	if b {
+		return triTrue
+	}
+
+	return triFalse
+}
+
+type filterOperand interface {
+	value(row []interface{}) interface{}
+}
+
+type columnOperand struct {
+	index int
+	enum  []string // values of ENUM column, binlog holds their numbers
+	set   []string // values of SET column, binlog holds their bitmasks
+	bit   bool     // BIT column, dump holds its bytes
+	exact bool     // binary or case-sensitive string column
+}
+
+func (o *columnOperand) value(row []interface{}) interface{} {
+	if o.index >= len(row) {
+		return nil
+	}
+
+	raw := row[o.index]
+	if o.bit && raw != nil {
+		if n, err := toBitValue(raw); err == nil {
+			raw = n
+		}
+	}
+
+	v := filterValue(raw)
+	if n, ok := v.(int64); ok && o.enum != nil {
+		if n < 1 || int(n) > len(o.enum) {
+			return ""
+		}
+
+		return o.enum[n-1]
+	}
+	if n, ok := v.(int64); ok && o.set != nil {
+		_, members, err := setMembers(n, o.set)
+		if err != nil {
+			return ""
+		}
+
+		return strings.Join(members, ",")
+	}
+	if s, ok := v.(string); ok && o.exact {
+		return exactString(s)
+	}
+
+	return v
+}
+
+type literalOperand struct {
+	v interface{}
+}
+
+func (o *literalOperand) value([]interface{}) interface{} {
+	return o.v
+}
+
+// exactString is the value of the binary or case-sensitive string column.
+type exactString string
+
+// filterValue normalizes the row value to nil, int64, uint64, float64,
+// decimal.Decimal or string.
+func filterValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, int64, uint64, float64, decimal.Decimal, string:
+		return v
+	case bool:
+		if v {
+			return int64(1)
+		}
+
+		return int64(0)
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return uint64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func negateFilterValue(v interface{}) (interface{}, bool) {
+	switch n := v.(type) {
+	case int64:
+		if n == math.MinInt64 {
+			return decimal.New(n, 0).Neg(), true
+		}
+
+		return -n, true
+	case uint64:
+		if n <= 1<<63 {
+			return int64(-n), true
+		}
+
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(n), 0).Neg(), true
+	case float64:
+		return -n, true
+	case decimal.Decimal:
+		return n.Neg(), true
+	default:
+		return nil, false
+	}
+}
+
+// compareFilterValues compares the normalized values: numbers numerically,
+// strings case-insensitively unless one of them is exact, a string and a number as numbers.
+// Returns false if the values are not comparable, e.g. one of them is null.
+func compareFilterValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	s, aIsString := filterString(a)
+	t, bIsString := filterString(b)
+	switch {
+	case aIsString && bIsString:
+		_, aIsExact := a.(exactString)
+		_, bIsExact := b.(exactString)
+		if aIsExact || bIsExact {
+			return strings.Compare(s, t), true
+		}
+
+		// The weights of _ci collations are the upper-cased characters.
+		return strings.Compare(strings.ToUpper(s), strings.ToUpper(t)), true
+	case aIsString:
+		n, ok := parseFilterNumber(s)
+		if !ok {
+			return 0, false
+		}
+		a = n
+	case bIsString:
+		n, ok := parseFilterNumber(t)
+		if !ok {
+			return 0, false
+		}
+		b = n
+	}
+
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return compareInt64(x, y), true
+		case uint64:
+			if x < 0 {
+				return -1, true
+			}
+
+			return compareUint64(uint64(x), y), true
+		}
+	case uint64:
+		switch y := b.(type) {
+		case uint64:
+			return compareUint64(x, y), true
+		case int64:
+			if y < 0 {
+				return 1, true
+			}
+
+			return compareUint64(x, uint64(y)), true
+		}
+	}
+
+	_, aIsFloat := a.(float64)
+	_, bIsFloat := b.(float64)
+	if aIsFloat || bIsFloat {
+		return compareFloat64(toFloat64(a), toFloat64(b)), true
+	}
+
+	return toFilterDecimal(a).Cmp(toFilterDecimal(b)), true
+}
+
+func filterString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case exactString:
+		return string(s), true
+	default:
+		return "", false
+	}
+}
+
+// parseFilterNumber parses the integer as int64 or uint64, the others as the exact decimal.
+func parseFilterNumber(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, true
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n, true
+	}
+	if d, err := decimal.NewFromString(s); err == nil {
+		return d, true
+	}
+
+	return nil, false
+}
+
+func toFloat64(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	case decimal.Decimal:
+		f, _ := v.Float64()
+
+		return f
+	default:
+		return 0
+	}
+}
+
+func toFilterDecimal(v interface{}) decimal.Decimal {
+	switch v := v.(type) {
+	case int64:
+		return decimal.New(v, 0)
+	case uint64:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(v), 0)
+	case decimal.Decimal:
+		return v
+	default:
+		return decimal.Decimal{}
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// bindFilterLiterals replaces the literals of the expression with placeholders
+// and returns their values in order of appearance. The parser stores the values
+// of the literals through the hooks of the ast package, the ones registered
+// by canal drop them, so the values are read before parsing.
+//
+// Adjacent strings are concatenated as in MySQL. NULL, TRUE and FALSE
+// following IS or IS NOT are kept as keywords.
+func bindFilterLiterals(expr string) (string, []interface{}, error) {
+	var sb strings.Builder
+	var literals []interface{}
+
+	var prev, prevPrev string // preceding words, upper-cased
+	afterString := false      // the last token is a string literal
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			sb.WriteByte(c)
+			i++
+
+			continue
+		case c == '\'' || c == '"':
+			s, n, err := unquoteFilterString(expr[i:])
+			if err != nil {
+				return "", nil, fmt.Errorf("%w at position %d", err, i)
+			}
+			i += n
+
+			if afterString {
+				literals[len(literals)-1] = literals[len(literals)-1].(string) + s
+			} else {
+				literals = append(literals, s)
+				sb.WriteByte('?')
+			}
+			afterString = true
+			prev, prevPrev = "", ""
+
+			continue
+		case c == '`':
+			n := filterIdentLen(expr[i:])
+			if n == -1 {
+				return "", nil, fmt.Errorf("unterminated identifier at position %d", i)
+			}
+			sb.WriteString(expr[i : i+n])
+			i += n
+		case c == '?':
+			return "", nil, fmt.Errorf("unexpected placeholder at position %d", i)
+		case isFilterWordPart(c):
+			start := i
+			for i < len(expr) && isFilterWordPart(expr[i]) {
+				i++
+			}
+			// The sign of the exponent, e.g. 1.5e-3.
+			if i+1 < len(expr) && (expr[i] == '+' || expr[i] == '-') && expr[i+1] >= '0' && expr[i+1] <= '9' &&
+				filterMantissaRegex.MatchString(expr[start:i]) {
+				i++
+				for i < len(expr) && isFilterWordPart(expr[i]) {
+					i++
+				}
+			}
+
+			word := expr[start:i]
+			upper := strings.ToUpper(word)
+			afterIs := prev == "IS" || prev == "NOT" && prevPrev == "IS"
+			if v, ok := filterNumberLiteral(word); ok {
+				literals = append(literals, v)
+				sb.WriteByte('?')
+			} else if v, ok := filterKeywordLiterals[upper]; ok && !afterIs {
+				literals = append(literals, v)
+				sb.WriteByte('?')
+			} else {
+				sb.WriteString(word)
+			}
+			prev, prevPrev = upper, prev
+			afterString = false
+
+			continue
+		default:
+			if n := filterCommentLen(expr[i:]); n > 0 {
+				sb.WriteString(expr[i : i+n])
+				i += n
+
+				continue
+			}
+
+			sb.WriteByte(c)
+			i++
+		}
+
+		prev, prevPrev = "", ""
+		afterString = false
+	}
+
+	return sb.String(), literals, nil
+}
+
+// filterKeywordLiterals are the values of the keyword literals.
+var filterKeywordLiterals = map[string]interface{}{
+	"NULL":  nil,
+	"TRUE":  int64(1),
+	"FALSE": int64(0),
+}
+
+var (
+	filterNumberRegex   = regexp.MustCompile(`^(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+	filterMantissaRegex = regexp.MustCompile(`^(\d+\.?\d*|\.\d+)[eE]$`)
+)
+
+// filterNumberLiteral parses the number literal: the integer as int64 or uint64,
+// the number with exponent as float64 and the others as the exact decimal.
+func filterNumberLiteral(s string) (interface{}, bool) {
+	if !filterNumberRegex.MatchString(s) {
+		return nil, false
+	}
+
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+
+		return f, err == nil
+	}
+
+	return parseFilterNumber(s)
+}
+
+// isFilterWordPart reports whether the byte belongs to the identifier,
+// the keyword or the number.
+func isFilterWordPart(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// filterIdentLen returns the length of the quoted identifier at the start of s,
+// the backtick is escaped by doubling. Returns -1 if the identifier is unterminated.
+func filterIdentLen(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] != '`' {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '`' {
+			i++
+
+			continue
+		}
+
+		return i + 1
+	}
+
+	return -1
+}
+
+// filterCommentLen returns the length of the comment at the start of s, zero if there is none.
+func filterCommentLen(s string) int {
+	switch {
+	case strings.HasPrefix(s, "/*"):
+		if end := strings.Index(s[2:], "*/"); end != -1 {
+			return end + 4
+		}
+
+		return len(s)
+	case strings.HasPrefix(s, "#"),
+		strings.HasPrefix(s, "--") && (len(s) == 2 || s[2] == ' ' || s[2] == '\t' || s[2] == '\n'):
+		if end := strings.IndexByte(s, '\n'); end != -1 {
+			return end
+		}
+
+		return len(s)
+	default:
+		return 0
+	}
+}
+
+// unquoteFilterString reads the quoted string at the start of s,
+// the quote is escaped by doubling or by backslash.
+// Returns the string and the number of bytes read.
+func unquoteFilterString(s string) (string, int, error) {
+	quote := s[0]
+
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'Z':
+				sb.WriteByte(0x1a)
+			case '0':
+				sb.WriteByte(0)
+			case '%', '_':
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte(s[i])
+			}
+		case c == quote && i+1 < len(s) && s[i+1] == quote:
+			sb.WriteByte(quote)
+			i++
+		case c == quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
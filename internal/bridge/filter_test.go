@@ -0,0 +1,344 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-mysql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOrdersTable() *schema.Table {
+	table := &schema.Table{
+		Schema: "shop",
+		Name:   "orders",
+	}
+	table.AddColumn("id", "bigint(20) unsigned", "", "")
+	table.AddColumn("region", "varchar(16)", "", "")
+	table.AddColumn("amount", "decimal(10,2)", "", "")
+	table.AddColumn("status", "enum('new','paid','cancelled')", "", "")
+	table.AddColumn("deleted_at", "datetime", "", "")
+	table.PKColumns = []int{0}
+
+	return table
+}
+
+func Test_rowFilter_match(t *testing.T) {
+	table := newTestOrdersTable()
+
+	tests := []struct {
+		name string
+		expr string
+		row  []interface{}
+		want bool
+	}{
+		{
+			name: "IsNull",
+			expr: "deleted_at IS NULL",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "IsNotNull",
+			expr: "deleted_at is not null",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(1), nil},
+			want: false,
+		},
+		{
+			name: "In",
+			expr: "region IN ('eu', 'us')",
+			row:  []interface{}{uint64(1), "us", 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "NotIn",
+			expr: "`region` NOT IN ('eu', 'us')",
+			row:  []interface{}{uint64(1), "us", 10.5, int64(1), nil},
+			want: false,
+		},
+		{
+			name: "NullNotIn",
+			expr: "region NOT IN ('eu')",
+			row:  []interface{}{uint64(1), nil, 10.5, int64(1), nil},
+			want: false,
+		},
+		{
+			name: "NumberCompare",
+			expr: "amount >= 10 AND id <> 2",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "NegativeNumber",
+			expr: "amount > -1.5e1",
+			row:  []interface{}{uint64(1), "eu", float64(-10), int64(1), nil},
+			want: true,
+		},
+		{
+			name: "StringNumber",
+			expr: "amount < 100",
+			row:  []interface{}{uint64(1), "eu", "99.99", int64(1), nil},
+			want: true,
+		},
+		{
+			name: "BinlogEnum",
+			expr: "status = 'paid'",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(2), nil},
+			want: true,
+		},
+		{
+			name: "DumpEnum",
+			expr: "status = 'paid'",
+			row:  []interface{}{uint64(1), "eu", 10.5, "paid", nil},
+			want: true,
+		},
+		{
+			name: "Precedence",
+			expr: "region = 'eu' OR region = 'us' AND amount > 100",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "Parentheses",
+			expr: "(region = 'eu' OR region = 'us') AND amount > 100",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(1), nil},
+			want: false,
+		},
+		{
+			name: "NullCompare",
+			expr: "region != 'eu'",
+			row:  []interface{}{uint64(1), nil, 10.5, int64(1), nil},
+			want: false,
+		},
+		{
+			name: "NotUnknown",
+			expr: "NOT region = 'eu'",
+			row:  []interface{}{uint64(1), nil, 10.5, int64(1), nil},
+			want: false,
+		},
+		{
+			name: "UnknownOrTrue",
+			expr: "region = 'eu' OR deleted_at IS NULL",
+			row:  []interface{}{uint64(1), nil, 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "QuotedString",
+			expr: `region = 'it''s' OR region = "a\"b"`,
+			row:  []interface{}{uint64(1), `a"b`, 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "AdjacentStrings",
+			expr: "region = 'e' 'u'",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "CaseInsensitive",
+			expr: "region = 'EU' AND status IN ('Paid')",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(2), nil},
+			want: true,
+		},
+		{
+			name: "CaseInsensitiveOrder",
+			expr: "region < '_'",
+			row:  []interface{}{uint64(1), "eu", 10.5, int64(1), nil},
+			want: true,
+		},
+		{
+			name: "ExactDecimal",
+			expr: "amount < 1234567890123456789.02",
+			row:  []interface{}{uint64(1), "eu", decimal.RequireFromString("1234567890123456789.01"), int64(1), nil},
+			want: true,
+		},
+		{
+			name: "ExactDecimalString",
+			expr: "amount <> 1234567890123456789.02",
+			row:  []interface{}{uint64(1), "eu", "1234567890123456789.01", int64(1), nil},
+			want: true,
+		},
+		{
+			name: "NullKeywords",
+			expr: "deleted_at IS NULL AND NOT region IS NOT NULL /* 'eu' */ AND status NOT IN (NULL, 'new')",
+			row:  []interface{}{uint64(1), nil, 10.5, int64(2), nil},
+			want: false,
+		},
+		{
+			name: "NullKeywordsKnown",
+			expr: "deleted_at IS NULL AND NOT region IS NOT NULL /* 'eu' */ AND status IN (NULL, 'paid')",
+			row:  []interface{}{uint64(1), nil, 10.5, int64(2), nil},
+			want: true,
+		},
+		{
+			name: "Truth",
+			expr: "amount AND TRUE",
+			row:  []interface{}{uint64(1), "eu", float64(0), int64(1), nil},
+			want: false,
+		},
+		{
+			name: "BinaryString",
+			expr: "region = 'eu'",
+			row:  []interface{}{uint64(1), []byte("eu"), 10.5, int64(1), nil},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newRowFilter(tt.expr, table)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, f.match(tt.row))
+		})
+	}
+}
+
+func Test_newRowFilter_Invalid(t *testing.T) {
+	table := newTestOrdersTable()
+
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "UnknownColumn", expr: "country = 'eu'"},
+		{name: "UnterminatedString", expr: "region = 'eu"},
+		{name: "UnterminatedIdentifier", expr: "`region = 'eu'"},
+		{name: "MissingOperand", expr: "region ="},
+		{name: "MissingParenthesis", expr: "(region = 'eu'"},
+		{name: "ExtraToken", expr: "region = 'eu' region"},
+		{name: "InWithoutList", expr: "region IN 'eu'"},
+		{name: "UnsupportedOperator", expr: "region LIKE 'e%'"},
+		{name: "UnexpectedCharacter", expr: "amount * 2 > 1"},
+		{name: "NegativeString", expr: "region = -'eu'"},
+		{name: "Empty", expr: " "},
+		{name: "Subquery", expr: "id IN (SELECT id FROM orders)"},
+		{name: "ExtraClause", expr: "region = 'eu' ORDER BY id"},
+		{name: "Union", expr: "region = 'eu' UNION SELECT 1"},
+		{name: "Function", expr: "LOWER(region) = 'eu'"},
+		{name: "HexLiteral", expr: "region = x'6575'"},
+		{name: "HexNumber", expr: "region = 0x6575"},
+		{name: "Placeholder", expr: "region = ?"},
+		{name: "QualifiedColumn", expr: "orders.region = 'eu'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRowFilter(tt.expr, table)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_rowFilter_rebuild(t *testing.T) {
+	table := newTestOrdersTable()
+
+	f, err := newRowFilter("status = 'paid' AND deleted_at IS NULL", table)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"status", "deleted_at"}, f.columns)
+
+	// A column is added before the filtered ones.
+	altered := &schema.Table{Schema: "shop", Name: "orders"}
+	altered.AddColumn("id", "bigint(20) unsigned", "", "")
+	altered.AddColumn("note", "text", "", "")
+	altered.AddColumn("status", "enum('new','paid','cancelled')", "", "")
+	altered.AddColumn("deleted_at", "datetime", "", "")
+
+	rebuilt, dropped, err := f.rebuild(altered)
+	require.NoError(t, err)
+	assert.Empty(t, dropped)
+	assert.True(t, rebuilt.match([]interface{}{uint64(1), "note", int64(2), nil}))
+
+	// A filtered column is dropped.
+	altered = &schema.Table{Schema: "shop", Name: "orders"}
+	altered.AddColumn("id", "bigint(20) unsigned", "", "")
+	altered.AddColumn("status", "enum('new','paid','cancelled')", "", "")
+
+	rebuilt, dropped, err = f.rebuild(altered)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deleted_at"}, dropped)
+	assert.Equal(t, f, rebuilt)
+
+	var nilFilter *rowFilter
+	rebuilt, dropped, err = nilFilter.rebuild(altered)
+	require.NoError(t, err)
+	assert.Nil(t, rebuilt)
+	assert.Empty(t, dropped)
+}
+
+func Test_rowFilter_match_SetAndBit(t *testing.T) {
+	table := &schema.Table{Schema: "city", Name: "users"}
+	table.AddColumn("id", "int(11)", "", "")
+	table.AddColumn("channels", "set('sms','email','push')", "", "")
+	table.AddColumn("active", "bit(1)", "", "")
+	table.PKColumns = []int{0}
+
+	f, err := newRowFilter("channels = 'sms,push' AND active = 1", table)
+	require.NoError(t, err)
+
+	// The binlog holds SET bitmask and BIT number, the dump holds the labels and the bytes.
+	assert.True(t, f.match([]interface{}{int64(1), int64(5), int64(1)}))
+	assert.True(t, f.match([]interface{}{int64(1), "sms,push", "\x01"}))
+	assert.False(t, f.match([]interface{}{int64(1), int64(4), int64(1)}))
+	assert.False(t, f.match([]interface{}{int64(1), "sms,push", "\x00"}))
+}
+
+func Test_rowFilter_match_CaseSensitive(t *testing.T) {
+	table := &schema.Table{Schema: "city", Name: "users"}
+	table.AddColumn("id", "int(11)", "", "")
+	table.AddColumn("token", "varbinary(16)", "", "")
+	table.AddColumn("login", "varchar(16)", "utf8mb4_bin", "")
+	table.AddColumn("name", "varchar(16)", "utf8mb4_general_ci", "")
+	table.PKColumns = []int{0}
+
+	f, err := newRowFilter("token = 'ab' AND login = 'root' AND name = 'ROOT'", table)
+	require.NoError(t, err)
+
+	assert.True(t, f.match([]interface{}{int64(1), []byte("ab"), "root", "Root"}))
+	assert.False(t, f.match([]interface{}{int64(1), []byte("AB"), "root", "Root"}))
+	assert.False(t, f.match([]interface{}{int64(1), []byte("ab"), "Root", "Root"}))
+}
+
+func Test_bindFilterLiterals(t *testing.T) {
+	tests := []struct {
+		name         string
+		expr         string
+		wantBound    string
+		wantLiterals []interface{}
+	}{
+		{
+			name:         "Numbers",
+			expr:         "a > 1 AND b < 18446744073709551615 AND c = 1.5 AND d > -1.5e-1",
+			wantBound:    "a > ? AND b < ? AND c = ? AND d > -?",
+			wantLiterals: []interface{}{int64(1), uint64(18446744073709551615), decimal.New(15, -1), 0.15},
+		},
+		{
+			name:         "Strings",
+			expr:         `a = 'it''s' ' ok' OR b = "a\"b"`,
+			wantBound:    "a = ?  OR b = ?",
+			wantLiterals: []interface{}{"it's ok", `a"b`},
+		},
+		{
+			name:         "Keywords",
+			expr:         "a IS NOT NULL AND b IS NULL AND c IN (NULL, TRUE, false)",
+			wantBound:    "a IS NOT NULL AND b IS NULL AND c IN (?, ?, ?)",
+			wantLiterals: []interface{}{nil, int64(1), int64(0)},
+		},
+		{
+			name:         "IdentifiersAndComments",
+			expr:         "`a'1` = 'x' /* 'y' */ -- 'z'",
+			wantBound:    "`a'1` = ? /* 'y' */ -- 'z'",
+			wantLiterals: []interface{}{"x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bound, literals, err := bindFilterLiterals(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBound, bound)
+			assert.Equal(t, tt.wantLiterals, literals)
+		})
+	}
+}
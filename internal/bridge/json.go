@@ -0,0 +1,145 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is the member or the array element selected by JSON path.
+type jsonPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses JSON path in MySQL notation, e.g. `$.address."zip code"` or "$.tags[0]".
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	s := strings.TrimSpace(path)
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("invalid JSON path %q: must start with $", path)
+	}
+	s = s[1:]
+
+	steps := make([]jsonPathStep, 0)
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			if strings.HasPrefix(s, `"`) {
+				end := strings.IndexByte(s[1:], '"')
+				if end == -1 {
+					return nil, fmt.Errorf("invalid JSON path %q: unterminated key", path)
+				}
+				steps = append(steps, jsonPathStep{key: s[1 : end+1]})
+				s = s[end+2:]
+
+				continue
+			}
+
+			end := strings.IndexAny(s, ".[")
+			if end == -1 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", path)
+			}
+			steps = append(steps, jsonPathStep{key: s[:end]})
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid JSON path %q: unterminated index", path)
+			}
+			index, err := strconv.Atoi(strings.TrimSpace(s[1:end]))
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: invalid index %q", path, s[1:end])
+			}
+			steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q: unexpected %q", path, s[0])
+		}
+	}
+
+	return steps, nil
+}
+
+// extractJSON returns the value selected by the path, null if it is missing.
+func extractJSON(v interface{}, path []jsonPathStep) interface{} {
+	for _, step := range path {
+		if step.isIndex {
+			arr, ok := v.([]interface{})
+			if !ok || step.index >= len(arr) {
+				return nil
+			}
+			v = arr[step.index]
+
+			continue
+		}
+
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[step.key]
+	}
+
+	return v
+}
+
+// toJSON decodes JSON document into maps, arrays and scalars.
+// The integer numbers are decoded as int64 or uint64, the others as float64.
+func toJSON(i interface{}) (interface{}, error) {
+	var data []byte
+	switch v := i.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		// The replacement of null, e.g. on_null: {}.
+		return i, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, &conversionError{value: string(data), target: "json", reason: "invalid"}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, &conversionError{value: string(data), target: "json", reason: "invalid"}
+	}
+
+	return convertJSONNumbers(v), nil
+}
+
+func convertJSONNumbers(v interface{}) interface{} {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := n.Float64()
+
+		return f
+	case []interface{}:
+		for i := range n {
+			n[i] = convertJSONNumbers(n[i])
+		}
+	case map[string]interface{}:
+		for k := range n {
+			n[k] = convertJSONNumbers(n[k])
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,198 @@
+package bridge
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/siddontang/go-mysql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+func Test_parseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []jsonPathStep
+		wantErr bool
+	}{
+		{path: "$", want: []jsonPathStep{}},
+		{path: "$.address.city", want: []jsonPathStep{{key: "address"}, {key: "city"}}},
+		{path: `$."zip code"`, want: []jsonPathStep{{key: "zip code"}}},
+		{path: "$.tags[1].name", want: []jsonPathStep{{key: "tags"}, {index: 1, isIndex: true}, {key: "name"}}},
+		{path: "$[0][2]", want: []jsonPathStep{{index: 0, isIndex: true}, {index: 2, isIndex: true}}},
+		{path: "address.city", wantErr: true},
+		{path: "$.", wantErr: true},
+		{path: "$.tags[", wantErr: true},
+		{path: "$.tags[-1]", wantErr: true},
+		{path: `$."city`, wantErr: true},
+		{path: "$tags", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_toJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "Object",
+			value: `{"id": 1, "big": 18446744073709551615, "rate": 0.5, "tags": ["a", null, true]}`,
+			want: map[string]interface{}{
+				"id":   int64(1),
+				"big":  uint64(18446744073709551615),
+				"rate": 0.5,
+				"tags": []interface{}{"a", nil, true},
+			},
+		},
+		{name: "Binlog", value: []byte(`[1, -2]`), want: []interface{}{int64(1), int64(-2)}},
+		{name: "Scalar", value: `"text"`, want: "text"},
+		{name: "Invalid", value: `{"id": }`, wantErr: true},
+		{name: "TrailingData", value: `{} {}`, wantErr: true},
+		{name: "Empty", value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toJSON(tt.value)
+			if tt.wantErr {
+				var invalid *conversionError
+				assert.True(t, errors.As(err, &invalid))
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_newRule_ExtractJSON(t *testing.T) {
+	table := &schema.Table{
+		Schema: "city",
+		Name:   "profiles",
+	}
+	table.AddColumn("id", "int(11)", "", "")
+	table.AddColumn("attrs", "json", "", "")
+	table.AddColumn("name", "varchar(64)", "", "")
+	table.PKColumns = []int{0}
+
+	newMapping := func(column config.MappingColumn) config.Mapping {
+		mapping := newTestMapping("profiles", []string{"attrs", "name"}, nil)
+		mapping.Dest.Column = map[string]config.MappingColumn{"attrs": column}
+
+		return mapping
+	}
+
+	column := config.MappingColumn{
+		Cast: "json",
+		Extract: []config.MappingJSONPath{
+			{Field: "city", Path: "$.address.city"},
+			{Field: "first_tag", Path: "$.tags[0]", OnNull: "none"},
+		},
+	}
+
+	r, err := newRule(newMapping(column), table)
+	require.NoError(t, err)
+
+	fields := make([]string, 0, len(r.attrs))
+	for i, attr := range r.attrs {
+		assert.Equal(t, uint64(i+1), attr.tupIndex)
+		fields = append(fields, attr.fieldName())
+	}
+	assert.Equal(t, []string{"attrs", "city", "first_tag", "name"}, fields)
+
+	row := []interface{}{int64(1), []byte(`{"address": {"city": "Paris"}, "tags": []}`), "bob"}
+	req, err := makeInsertRequest(r, row)
+	require.NoError(t, err)
+	assert.Equal(t, []reqArg{
+		{field: 1, value: map[string]interface{}{
+			"address": map[string]interface{}{"city": "Paris"},
+			"tags":    []interface{}{},
+		}},
+		{field: 2, value: "Paris"},
+		{field: 3, value: "none"},
+		{field: 4, value: "bob"},
+	}, req.args)
+
+	// Invalid JSON document is handled by on_invalid policy of the column.
+	row = []interface{}{int64(2), "{", "alice"}
+	_, err = makeInsertRequest(r, row)
+	assert.EqualError(t, err, `row [2]: column attrs: could not cast "{" to json: invalid`)
+
+	column.OnNull = `{}`
+	column.OnInvalid = "default"
+	r, err = newRule(newMapping(column), table)
+	require.NoError(t, err)
+
+	req, err = makeInsertRequest(r, row)
+	require.NoError(t, err)
+	assert.Equal(t, []reqArg{
+		{field: 1, value: map[string]interface{}{}},
+		{field: 2, value: nil},
+		{field: 3, value: "none"},
+		{field: 4, value: "alice"},
+	}, req.args)
+
+	column.OnInvalid = "log"
+	r, err = newRule(newMapping(column), table)
+	require.NoError(t, err)
+
+	req, err = makeInsertRequest(r, row)
+	require.NoError(t, err)
+	assert.Nil(t, req)
+}
+
+func Test_newRule_ExtractJSON_Invalid(t *testing.T) {
+	table := newTestUsersTable()
+
+	tests := []struct {
+		name    string
+		column  string
+		mapping config.MappingColumn
+	}{
+		{
+			name:    "KeyColumn",
+			column:  "id",
+			mapping: config.MappingColumn{Extract: []config.MappingJSONPath{{Field: "x", Path: "$.x"}}},
+		},
+		{
+			name:    "NoField",
+			column:  "username",
+			mapping: config.MappingColumn{Extract: []config.MappingJSONPath{{Path: "$.x"}}},
+		},
+		{
+			name:    "InvalidPath",
+			column:  "username",
+			mapping: config.MappingColumn{Extract: []config.MappingJSONPath{{Field: "x", Path: "x"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := withColumn(newTestMapping("users", []string{"username"}, nil), tt.column, tt.mapping)
+
+			_, err := newRule(mapping, table)
+			assert.Error(t, err)
+		})
+	}
+}
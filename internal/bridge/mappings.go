@@ -0,0 +1,127 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/siddontang/go-mysql/client"
+	"github.com/siddontang/go-mysql/schema"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+const listSchemaTablesQuery = `
+SELECT TABLE_NAME
+FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+ORDER BY TABLE_NAME
+`
+
+// GenerateMappings returns the mappings of all the tables of MySQL schema,
+// each table is mapped to the space of the same name.
+// The tables without primary key are skipped.
+func GenerateMappings(cfg *config.Config, db string, logger zerolog.Logger) ([]config.Mapping, error) {
+	src := cfg.Replication.ConnectionSrc
+	conn, err := client.Connect(src.Addr, src.User, src.Password, "")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	res, err := conn.Execute(listSchemaTablesQuery, db)
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := make([]config.Mapping, 0, res.RowNumber())
+	for i := 0; i < res.RowNumber(); i++ {
+		name, err := res.GetString(i, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		table, err := schema.NewTable(conn, db, name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read table %s.%s: %w", db, name, err)
+		}
+
+		if len(table.PKColumns) == 0 {
+			logger.Warn().
+				Str("schema", db).
+				Str("table", name).
+				Msg("table has no primary key, skip it")
+
+			continue
+		}
+
+		nullable, err := queryNullableColumns(conn, db, name)
+		if err != nil {
+			return nil, err
+		}
+
+		mappings = append(mappings, newTableMapping(table, nullable))
+	}
+
+	return mappings, nil
+}
+
+// newTableMapping maps all the columns of the table. Unsigned integer columns are cast
+// to unsigned, nullable indexed columns are replaced by zero values on null.
+func newTableMapping(table *schema.Table, nullable map[string]bool) config.Mapping {
+	var m config.Mapping
+	m.Source.Schema = table.Schema
+	m.Source.Table = table.Name
+	m.Dest.Space = table.Name
+
+	indexed := make(map[string]bool)
+	for _, index := range table.Indexes {
+		for _, name := range index.Columns {
+			indexed[name] = true
+		}
+	}
+
+	pks := make(map[int]bool, len(table.PKColumns))
+	for _, idx := range table.PKColumns {
+		pks[idx] = true
+	}
+
+	for i, col := range table.Columns {
+		// Primary keys are replicated anyway.
+		if !pks[i] {
+			m.Source.Columns = append(m.Source.Columns, col.Name)
+		}
+
+		var column config.MappingColumn
+		// DECIMAL and DOUBLE UNSIGNED values may be fractional.
+		if t := attrType(col.Type); col.IsUnsigned && (t == typeNumber || t == typeMediumInt) {
+			column.Cast = "unsigned"
+		}
+		if nullable[col.Name] && indexed[col.Name] {
+			column.OnNull = zeroValue(attrType(col.Type))
+		}
+
+		if column.Cast != "" || column.OnNull != nil {
+			if m.Dest.Column == nil {
+				m.Dest.Column = make(map[string]config.MappingColumn)
+			}
+			m.Dest.Column[col.Name] = column
+		}
+	}
+
+	return m
+}
+
+// zeroValue returns the value replacing null of the column type,
+// nil if there is no suitable value.
+func zeroValue(t attrType) interface{} {
+	switch t {
+	case typeNumber, typeMediumInt, typeFloat, typeDecimal, typeEnum, typeSet, typeBit:
+		return 0
+	case typeString, typeDatetime, typeTimestamp, typeDate, typeTime, typeBinary:
+		return ""
+	default:
+		return nil
+	}
+}
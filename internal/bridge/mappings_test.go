@@ -0,0 +1,79 @@
+package bridge
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/siddontang/go-mysql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+func Test_newTableMapping(t *testing.T) {
+	table := newTestUsersTable()
+	table.AddColumn("age", "int(11)", "", "")
+	table.AddColumn("rating", "float", "", "")
+	table.AddColumn("balance", "decimal(10,2) unsigned", "", "")
+	table.AddColumn("score", "double unsigned", "", "")
+	table.AddColumn("visits", "mediumint(8) unsigned", "", "")
+	table.Indexes = []*schema.Index{
+		{Name: "PRIMARY", Columns: []string{"id"}},
+		{Name: "email_idx", Columns: []string{"email"}},
+		{Name: "rating_idx", Columns: []string{"rating", "age"}},
+	}
+
+	nullable := map[string]bool{
+		"email":  true,
+		"rating": true,
+		"age":    false,
+	}
+
+	got := newTableMapping(table, nullable)
+
+	assert.Equal(t, "city", got.Source.Schema)
+	assert.Equal(t, "users", got.Source.Table)
+	assert.False(t, got.Source.Regex)
+	assert.Equal(t, []string{"username", "email", "age", "rating", "balance", "score", "visits"}, got.Source.Columns)
+	assert.Equal(t, "users", got.Dest.Space)
+	assert.Equal(t, map[string]config.MappingColumn{
+		"id":     {Cast: "unsigned"},
+		"email":  {OnNull: ""},
+		"rating": {OnNull: 0},
+		"visits": {Cast: "unsigned"},
+	}, got.Dest.Column)
+
+	_, err := newRule(got, table)
+	require.NoError(t, err)
+}
+
+func Test_newTableMapping_YAML(t *testing.T) {
+	got := newTableMapping(newTestUsersTable(), nil)
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	err := enc.Encode(&got)
+	require.NoError(t, err)
+
+	want := `source:
+  schema: city
+  table: users
+  columns:
+  - username
+  - email
+dest:
+  space: users
+  column:
+    id:
+      cast: unsigned
+`
+	assert.Equal(t, want, out.String())
+
+	var m config.Mapping
+	err = yaml.Unmarshal(out.Bytes(), &m)
+	require.NoError(t, err)
+	assert.Equal(t, got, m)
+}
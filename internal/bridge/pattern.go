@@ -0,0 +1,191 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+var ErrSharedSpace = errors.New("space is shared by several tables")
+
+const listTablesQuery = `
+SELECT TABLE_SCHEMA, TABLE_NAME
+FROM information_schema.TABLES
+WHERE TABLE_TYPE = 'BASE TABLE'
+`
+
+// ruleTemplate is the mapping of the tables matching the patterns,
+// the rules are compiled when a matching table appears.
+type ruleTemplate struct {
+	mapping config.Mapping
+	schema  *regexp.Regexp
+	table   *regexp.Regexp
+}
+
+func newRuleTemplate(mapping config.Mapping) (*ruleTemplate, error) {
+	source := mapping.Source
+
+	schema, err := regexp.Compile(anchorRegex(source.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema pattern %q: %w", source.Schema, err)
+	}
+
+	table, err := regexp.Compile(anchorRegex(source.Table))
+	if err != nil {
+		return nil, fmt.Errorf("invalid table pattern %q: %w", source.Table, err)
+	}
+
+	return &ruleTemplate{
+		mapping: mapping,
+		schema:  schema,
+		table:   table,
+	}, nil
+}
+
+func (t *ruleTemplate) match(schema, table string) bool {
+	return t.schema.MatchString(schema) && t.table.MatchString(table)
+}
+
+func anchorRegex(expr string) string {
+	return "^(?:" + expr + ")$"
+}
+
+// includeTableRegex returns the regex canal filters the tables of the mapping with,
+// canal matches it against "schema.table".
+func includeTableRegex(mapping config.Mapping) string {
+	source := mapping.Source
+	if source.Regex {
+		return anchorRegex("(?:" + source.Schema + `)\.(?:` + source.Table + ")")
+	}
+
+	return anchorRegex(regexp.QuoteMeta(source.Schema) + `\.` + regexp.QuoteMeta(source.Table))
+}
+
+// resolveTemplates compiles the rules of the existing tables matching the templates.
+func (b *Bridge) resolveTemplates(rules map[string][]*rule) error {
+	if len(b.templates) == 0 {
+		return nil
+	}
+
+	res, err := b.canal.Execute(listTablesQuery)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < res.RowNumber(); i++ {
+		schema, err := res.GetString(i, 0)
+		if err != nil {
+			return err
+		}
+
+		table, err := res.GetString(i, 1)
+		if err != nil {
+			return err
+		}
+
+		tableRules, err := b.compileTemplates(schema, table)
+		if err != nil {
+			return err
+		}
+
+		key := ruleKey(schema, table)
+		rules[key] = append(rules[key], tableRules...)
+	}
+
+	return nil
+}
+
+// compileTemplates compiles the rules of all the templates matching the table.
+func (b *Bridge) compileTemplates(schema, table string) ([]*rule, error) {
+	var rules []*rule
+	for _, tpl := range b.templates {
+		if !tpl.match(schema, table) {
+			continue
+		}
+
+		tableInfo, err := b.canal.GetTable(schema, table)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := newRule(tpl.mapping, tableInfo)
+		if err != nil {
+			return nil, fmt.Errorf("mapping %s.%s of table %s.%s: %w",
+				tpl.mapping.Source.Schema, tpl.mapping.Source.Table, schema, table, err)
+		}
+		r.logger = &b.logger
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// tableRules returns the rules of the table, the rules of a table
+// matching the templates are compiled on first access.
+func (b *Bridge) tableRules(schema, table string) ([]*rule, error) {
+	key := ruleKey(schema, table)
+
+	b.rulesMu.RLock()
+	rules, ok := b.rules[key]
+	b.rulesMu.RUnlock()
+	if ok {
+		return rules, nil
+	}
+
+	rules, err := b.compileTemplates(schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, ErrRuleNotExist
+	}
+
+	b.rulesMu.Lock()
+	defer b.rulesMu.Unlock()
+
+	// The table may be resolved concurrently.
+	if existing, ok := b.rules[key]; ok {
+		return existing, nil
+	}
+	b.rules[key] = rules
+
+	b.logger.Info().
+		Str("schema", schema).
+		Str("table", table).
+		Msg("new table matches the mapping")
+
+	return rules, nil
+}
+
+// tables returns the rules of all the known tables.
+func (b *Bridge) tables() [][]*rule {
+	b.rulesMu.RLock()
+	defer b.rulesMu.RUnlock()
+
+	tables := make([][]*rule, 0, len(b.rules))
+	for _, rules := range b.rules {
+		tables = append(tables, rules)
+	}
+
+	return tables
+}
+
+// sharedSpace reports whether several known tables are replicated to the space,
+// the tables matching the templates are counted once they are resolved.
+func (b *Bridge) sharedSpace(space string) bool {
+	n := 0
+	for _, rules := range b.tables() {
+		for _, r := range rules {
+			if r.space == space {
+				n++
+
+				break
+			}
+		}
+	}
+
+	return n > 1
+}
@@ -0,0 +1,107 @@
+package bridge
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+func newTestPatternMapping(schema, table, space string) config.Mapping {
+	var m config.Mapping
+	m.Source.Schema = schema
+	m.Source.Table = table
+	m.Source.Regex = true
+	m.Dest.Space = space
+
+	return m
+}
+
+func Test_includeTableRegex(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  config.Mapping
+		matches  []string
+		excludes []string
+	}{
+		{
+			name:     "Literal",
+			mapping:  newTestMapping("users", nil, nil),
+			matches:  []string{"city.users"},
+			excludes: []string{"city.users_archive", "old_city.users", "cityXusers"},
+		},
+		{
+			name:     "Pattern",
+			mapping:  newTestPatternMapping("shop", `orders_\d{3}`, "orders"),
+			matches:  []string{"shop.orders_000", "shop.orders_255"},
+			excludes: []string{"shop.orders", "shop.orders_old", "shop.orders_0001", "eshop.orders_000"},
+		},
+		{
+			name:     "Alternation",
+			mapping:  newTestPatternMapping("tenant_a|tenant_b", "users", "users"),
+			matches:  []string{"tenant_a.users", "tenant_b.users"},
+			excludes: []string{"tenant_a.users_b", "tenant_a.tenant_b.users", "tenant_c.users"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := regexp.Compile(includeTableRegex(tt.mapping))
+			require.NoError(t, err)
+
+			for _, key := range tt.matches {
+				assert.True(t, reg.MatchString(key), key)
+			}
+			for _, key := range tt.excludes {
+				assert.False(t, reg.MatchString(key), key)
+			}
+		})
+	}
+}
+
+func Test_ruleTemplate_match(t *testing.T) {
+	tpl, err := newRuleTemplate(newTestPatternMapping(`tenant_\d+`, `orders_\d+`, "orders"))
+	require.NoError(t, err)
+
+	assert.True(t, tpl.match("tenant_1", "orders_001"))
+	assert.False(t, tpl.match("tenant_1", "orders"))
+	assert.False(t, tpl.match("tenant_1_old", "orders_001"))
+	assert.False(t, tpl.match("city", "orders_001"))
+
+	_, err = newRuleTemplate(newTestPatternMapping("city", "orders_(", "orders"))
+	assert.Error(t, err)
+}
+
+func TestBridge_sharedSpace(t *testing.T) {
+	users := newTestUsersRule()
+	users.space = "users"
+
+	archive := newTestUsersRule()
+	archive.table = "users_archive"
+	archive.space = "users"
+
+	logins := newTestUsersRule()
+	logins.table = "logins"
+	logins.space = "logins"
+
+	b := newTestBridge(users, archive, logins)
+	assert.True(t, b.sharedSpace("users"))
+	assert.False(t, b.sharedSpace("logins"))
+
+	tpl, err := newRuleTemplate(newTestPatternMapping("city", `logins_\d+`, "logins"))
+	require.NoError(t, err)
+
+	// The template space is shared only when several tables are resolved to it.
+	b.templates = append(b.templates, tpl)
+	assert.False(t, b.sharedSpace("logins"))
+
+	resolved := newTestUsersRule()
+	resolved.table = "logins_001"
+	resolved.space = "logins"
+	b.rules[ruleKey(resolved.schema, resolved.table)] = []*rule{resolved}
+	assert.True(t, b.sharedSpace("logins"))
+}
@@ -0,0 +1,211 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/siddontang/go-mysql/client"
+	tnt "github.com/viciious/go-tarantool"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/metrics"
+)
+
+// repairKeys is sent to sync to replace the divergent tuples by the rows
+// read from MySQL and to delete the tuples absent in MySQL.
+//
+// The rows may be ahead of the synced binlog position, so until the binlog
+// reaches the position the rows are read at, the inserts of the repaired keys
+// are applied as replace and the position is not saved.
+type repairKeys struct {
+	rule     *rule
+	replaces []*request
+	deletes  []*request
+	keys     map[string]struct{}
+	until    position
+}
+
+func (m *repairKeys) queries() []tnt.Query {
+	queries := makeQueries(&batch{
+		action: actionInsert,
+		reqs:   m.replaces,
+	})
+
+	return append(queries, makeQueries(&batch{
+		action: actionDelete,
+		reqs:   m.deletes,
+	})...)
+}
+
+// StartVerify compares the table with its space in background,
+// the divergent tuples are repaired if repair is true.
+// All the tables are verified if schema and table are empty.
+//
+// The result is logged, returns error if the verification can not be started.
+func (b *Bridge) StartVerify(schema, table string, repair bool) error {
+	if repair && !b.Running() {
+		return ErrNotRunning
+	}
+
+	var rules []*rule
+	if schema == "" && table == "" {
+		for _, tableRules := range b.tables() {
+			rules = append(rules, tableRules...)
+		}
+	} else {
+		tableRules, err := b.tableRules(schema, table)
+		if err != nil {
+			return err
+		}
+
+		rules = append(rules, tableRules...)
+	}
+
+	go func() {
+		err := b.verifyRules(rules, repair)
+		if err != nil {
+			b.logger.Err(err).Msg("verification failed")
+		}
+	}()
+
+	return nil
+}
+
+func (b *Bridge) verifyRules(rules []*rule, repair bool) error {
+	conn, err := b.connectSource()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for _, r := range rules {
+		report, err := b.verifyRule(b.ctx, conn, r, repair)
+		if err != nil {
+			return fmt.Errorf("verify %s.%s: %w", r.schema, r.table, err)
+		}
+
+		event := b.logger.Info()
+		if !report.Consistent() {
+			event = b.logger.Warn()
+		}
+
+		event.
+			EmbedObject(report).
+			Bool("repair", repair).
+			Msg("verification done")
+	}
+
+	return nil
+}
+
+// readRepair reads the rows by the keys of the divergent tuples
+// and makes the requests repairing the tuples.
+func (b *Bridge) readRepair(conn *client.Conn, r *rule, keys [][]interface{}) (*repairKeys, error) {
+	rows, err := selectByKeys(conn, r, keys, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The changes up to this position may be not applied yet.
+	until, err := b.masterPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*request, len(rows))
+	for _, row := range rows {
+		// The tuple of the filtered out row is deleted.
+		if !r.match(row) {
+			continue
+		}
+
+		req, err := makeInsertRequest(r, row)
+		if err != nil {
+			return nil, err
+		}
+		if req == nil {
+			continue
+		}
+
+		// The tuple may exist or not, replace is suitable for both.
+		req.onConflict = conflictReplace
+		found[keyString(makeTuple(req)[:len(r.pks)])] = req
+	}
+
+	msg := &repairKeys{
+		rule:  r,
+		keys:  make(map[string]struct{}, len(keys)),
+		until: until,
+	}
+	for _, key := range keys {
+		ks := keyString(key)
+		msg.keys[ks] = struct{}{}
+
+		if req, ok := found[ks]; ok {
+			msg.replaces = append(msg.replaces, req)
+		} else {
+			msg.deletes = append(msg.deletes, makeDeleteRequestByKey(r, key))
+		}
+	}
+
+	return msg, nil
+}
+
+// logRepair logs and counts the repaired tuples.
+func (b *Bridge) logRepair(msg *repairKeys) {
+	r := msg.rule
+
+	table := ruleKey(r.schema, r.table)
+	metrics.AddRepairedTuples(table, string(actionInsert), len(msg.replaces))
+	metrics.AddRepairedTuples(table, string(actionDelete), len(msg.deletes))
+
+	b.logger.Info().
+		Str("schema", r.schema).
+		Str("table", r.table).
+		Str("space", r.space).
+		Int("replaced", len(msg.replaces)).
+		Int("deleted", len(msg.deletes)).
+		Msg("divergent tuples repaired")
+}
+
+// replaceRepaired applies the inserts of the repaired keys as replace,
+// the repaired tuples may already contain the inserted rows.
+func replaceRepaired(repaired []*repairKeys, batches []*batch) {
+	if len(repaired) == 0 {
+		return
+	}
+
+	for _, bt := range batches {
+		for _, req := range bt.reqs {
+			if req.action != actionInsert {
+				continue
+			}
+
+			key := make([]interface{}, 0, len(req.keys))
+			for _, k := range req.keys {
+				key = append(key, k.value)
+			}
+			ks := keyString(key)
+
+			for _, m := range repaired {
+				if _, ok := m.keys[ks]; ok && m.rule.space == req.space {
+					req.onConflict = conflictReplace
+
+					break
+				}
+			}
+		}
+	}
+}
+
+// dropRepaired returns the repairs which rows are read ahead of the position.
+func dropRepaired(repaired []*repairKeys, pos position) []*repairKeys {
+	left := repaired[:0]
+	for _, m := range repaired {
+		if !pos.contains(m.until) {
+			left = append(left, m)
+		}
+	}
+
+	return left
+}
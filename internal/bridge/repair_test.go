@@ -0,0 +1,56 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_replaceRepaired(t *testing.T) {
+	repaired := []*repairKeys{
+		{
+			rule: &rule{space: "users"},
+			keys: map[string]struct{}{
+				keyString([]interface{}{uint64(1)}): {},
+			},
+		},
+	}
+
+	batches := []*batch{
+		{
+			action: actionInsert,
+			reqs: []*request{
+				{action: actionInsert, space: "users", keys: []reqArg{{field: 0, value: uint64(1)}}},
+				{action: actionInsert, space: "users", keys: []reqArg{{field: 0, value: uint64(2)}}},
+				{action: actionInsert, space: "logins", keys: []reqArg{{field: 0, value: uint64(1)}}},
+			},
+		},
+		{
+			action: actionUpdate,
+			reqs: []*request{
+				{action: actionDelete, space: "users", keys: []reqArg{{field: 0, value: uint64(1)}}},
+				{action: actionInsert, space: "users", keys: []reqArg{{field: 0, value: int64(1)}}},
+			},
+		},
+	}
+
+	replaceRepaired(repaired, batches)
+
+	assert.Equal(t, conflictReplace, batches[0].reqs[0].onConflict)
+	assert.Equal(t, conflictError, batches[0].reqs[1].onConflict)
+	assert.Equal(t, conflictError, batches[0].reqs[2].onConflict)
+	assert.Equal(t, conflictError, batches[1].reqs[0].onConflict)
+	assert.Equal(t, conflictReplace, batches[1].reqs[1].onConflict)
+}
+
+func Test_dropRepaired(t *testing.T) {
+	first := &repairKeys{until: newBinlogPos(mysql.Position{Name: "mysql-bin.000001", Pos: 100})}
+	second := &repairKeys{until: newBinlogPos(mysql.Position{Name: "mysql-bin.000002", Pos: 4})}
+
+	got := dropRepaired([]*repairKeys{first, second}, newBinlogPos(mysql.Position{Name: "mysql-bin.000001", Pos: 100}))
+	assert.Equal(t, []*repairKeys{second}, got)
+
+	got = dropRepaired(got, newBinlogPos(mysql.Position{Name: "mysql-bin.000002", Pos: 50}))
+	assert.Empty(t, got)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,11 +24,18 @@ const eventsBufSize = 4096
 var ErrRuleNotExist = errors.New("rule is not exist")
 
 type Bridge struct {
-	rules map[string]*rule
+	rules     map[string][]*rule // a table may be replicated to several spaces
+	rulesMu   *sync.RWMutex
+	templates []*ruleTemplate // mappings of the table patterns
 
 	canal      *canal.Canal
 	tntClient  *tarantool.Client
 	stateSaver stateSaver
+	txSaver    txStateSaver // not nil in exactly-once mode
+
+	snapshotCfg *snapshotConfig
+	dump        *dumpState
+	resyncs     *sync.Map // spaces being resynced
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -42,19 +50,11 @@ type Bridge struct {
 }
 
 func New(cfg *config.Config, logger zerolog.Logger) (*Bridge, error) {
-	b := &Bridge{
-		logger:    logger,
-		dumping:   atomic.NewBool(false),
-		running:   atomic.NewBool(false),
-		syncedAt:  atomic.NewInt64(0),
-		syncCh:    make(chan interface{}, eventsBufSize),
-		closeOnce: &sync.Once{},
+	b, err := newBridge(cfg, logger)
+	if err != nil {
+		return nil, err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	b.ctx = ctx
-	b.cancel = cancel
-
 	if err := b.newStateSaver(cfg); err != nil {
 		return nil, err
 	}
@@ -67,92 +67,124 @@ func New(cfg *config.Config, logger zerolog.Logger) (*Bridge, error) {
 		return nil, err
 	}
 
+	if cfg.Replication.ConnectionDest.CreateSpaces {
+		if err := b.createSpaces(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Incompatible spaces fail the replication after the dump is started.
+	if err := b.validateSpaces(); err != nil {
+		return nil, err
+	}
+
 	// We must use binlog full row image.
-	if err := b.canal.CheckBinlogRowImage("FULL"); err != nil {
+	if err := b.checkBinlogRowImage(cfg.Replication.ConnectionSrc.Flavor, "FULL"); err != nil {
 		return nil, err
 	}
 
+	return b, nil
+}
+
+func newBridge(cfg *config.Config, logger zerolog.Logger) (*Bridge, error) {
+	b := &Bridge{
+		logger:    logger,
+		dumping:   atomic.NewBool(false),
+		running:   atomic.NewBool(false),
+		syncedAt:  atomic.NewInt64(0),
+		rulesMu:   &sync.RWMutex{},
+		dump:      newDumpState(nil),
+		resyncs:   &sync.Map{},
+		syncCh:    make(chan interface{}, eventsBufSize),
+		closeOnce: &sync.Once{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.ctx = ctx
+	b.cancel = cancel
+
+	switch flavor := cfg.Replication.ConnectionSrc.Flavor; flavor {
+	case config.FlavorMySQL, config.FlavorMariaDB:
+	default:
+		return nil, fmt.Errorf("unsupported flavor: %s", flavor)
+	}
+
 	b.newTarantoolClient(cfg)
 
 	return b, nil
 }
 
 func (b *Bridge) newStateSaver(cfg *config.Config) error {
-	fs, err := newFileSaver(cfg.App.DataFile, cfg.Replication.GTIDMode)
+	var saver stateSaver
+	var err error
+
+	stateCfg := cfg.App.State
+	flavor := cfg.Replication.ConnectionSrc.Flavor
+	switch stateCfg.Storage {
+	case config.StateStorageFile, "":
+		saver, err = newFileSaver(cfg.App.DataFile, cfg.Replication.GTIDMode, flavor)
+	case config.StateStorageTarantool:
+		saver, err = newTarantoolSaver(b.tntClient, stateCfg.Space, stateCfg.Key, cfg.Replication.GTIDMode, flavor)
+	default:
+		err = fmt.Errorf("unsupported state storage: %s", stateCfg.Storage)
+	}
 	if err != nil {
 		return err
 	}
 
-	_, err = fs.load()
+	_, err = saver.load()
 	if err != nil {
 		return err
 	}
 
-	b.stateSaver = fs
+	b.stateSaver = saver
+
+	if cfg.Replication.ExactlyOnce {
+		txSaver, ok := saver.(txStateSaver)
+		if !ok {
+			return fmt.Errorf("exactly-once mode requires %s state storage", config.StateStorageTarantool)
+		}
+
+		b.txSaver = txSaver
+	}
 
 	return nil
 }
 
 func (b *Bridge) newRules(cfg *config.Config) error {
-	rules := make(map[string]*rule, len(cfg.Replication.Mappings))
+	rules := make(map[string][]*rule, len(cfg.Replication.Mappings))
 	for _, mapping := range cfg.Replication.Mappings {
 		source := mapping.Source
-		colmap := mapping.Dest.Column
-
-		tableInfo, err := b.canal.GetTable(source.Schema, source.Table)
-		if err != nil {
-			return err
-		}
-
-		pks := newAttrsFromPKs(tableInfo)
-		if len(pks) == 0 {
-			return fmt.Errorf("no primary keys found, schema: %s, table: %s", source.Schema, source.Table)
-		}
-		for _, pk := range pks {
-			if m, ok := colmap[pk.name]; ok {
-				pk.castTo(castTypeFromString(m.Cast))
-				pk.onNull = m.OnNull
-			}
-		}
-
-		attrs := make([]*attribute, 0, len(source.Columns))
-		for i, name := range source.Columns {
-			isPK := false
-			for _, pk := range pks {
-				if name == pk.name {
-					isPK = true
-
-					break
-				}
+		if source.Regex {
+			tpl, err := newRuleTemplate(mapping)
+			if err != nil {
+				return err
 			}
 
-			if !isPK {
-				tupIndex := uint64(i + len(pks))
-				attr, err := newAttr(tableInfo, tupIndex, name)
-				if err != nil {
-					return err
-				}
+			b.templates = append(b.templates, tpl)
 
-				if m, ok := colmap[name]; ok {
-					attr.castTo(castTypeFromString(m.Cast))
-					attr.onNull = m.OnNull
-				}
+			continue
+		}
 
-				attrs = append(attrs, attr)
-			}
+		tableInfo, err := b.canal.GetTable(source.Schema, source.Table)
+		if err != nil {
+			return err
 		}
 
-		rule := &rule{
-			schema:    source.Schema,
-			table:     source.Table,
-			pks:       pks,
-			attrs:     attrs,
-			space:     mapping.Dest.Space,
-			tableInfo: tableInfo,
+		rule, err := newRule(mapping, tableInfo)
+		if err != nil {
+			return err
 		}
+		rule.logger = &b.logger
 
+		// The table may be replicated to several spaces.
 		key := ruleKey(rule.schema, rule.table)
-		rules[key] = rule
+		rules[key] = append(rules[key], rule)
+	}
+
+	// The tables matching the patterns later are resolved on the first event.
+	if err := b.resolveTemplates(rules); err != nil {
+		return err
 	}
 
 	b.rules = rules
@@ -161,8 +193,15 @@ func (b *Bridge) newRules(cfg *config.Config) error {
 	return nil
 }
 
+// updateRule rebuilds the rules of the altered table,
+// so the columns are read from the right row positions.
 func (b *Bridge) updateRule(schema, table string) error {
-	rule, ok := b.rules[ruleKey(schema, table)]
+	// The rules of the tables matching the patterns are compiled
+	// with the actual table info when resolved.
+	key := ruleKey(schema, table)
+	b.rulesMu.RLock()
+	rules, ok := b.rules[key]
+	b.rulesMu.RUnlock()
 	if !ok {
 		return ErrRuleNotExist
 	}
@@ -172,7 +211,42 @@ func (b *Bridge) updateRule(schema, table string) error {
 		return err
 	}
 
-	rule.tableInfo = tableInfo
+	rebuilt := make([]*rule, 0, len(rules))
+	for _, r := range rules {
+		nr, dropped, err := r.rebuild(tableInfo)
+		if err != nil {
+			return fmt.Errorf("rebuild mapping to space %s: %w", r.space, err)
+		}
+
+		switch {
+		case nr.paused && !r.paused:
+			b.logger.Error().
+				Str("schema", schema).
+				Str("table", table).
+				Str("space", nr.space).
+				Strs("columns", dropped).
+				Msg("mapped columns are dropped, replication to the space is paused")
+		case !nr.paused && r.paused:
+			b.logger.Info().
+				Str("schema", schema).
+				Str("table", table).
+				Str("space", nr.space).
+				Msg("mapped columns are back, replication to the space is resumed")
+		case len(dropped) > 0:
+			b.logger.Warn().
+				Str("schema", schema).
+				Str("table", table).
+				Str("space", nr.space).
+				Strs("columns", dropped).
+				Msg("mapped columns are dropped, the fields are replicated as null")
+		}
+
+		rebuilt = append(rebuilt, nr)
+	}
+
+	b.rulesMu.Lock()
+	b.rules[key] = rebuilt
+	b.rulesMu.Unlock()
 
 	return nil
 }
@@ -188,19 +262,46 @@ func (b *Bridge) newCanal(cfg *config.Config) error {
 	canalCfg.User = myCfg.User
 	canalCfg.Password = myCfg.Password
 	canalCfg.Charset = myCfg.Charset
-	canalCfg.Flavor = mysql.MySQLFlavor // TODO: support MariaDB
+	canalCfg.Flavor = myCfg.Flavor
 	canalCfg.SemiSyncEnabled = false
+	// TIMESTAMP values are read in UTC both from the binlog and the dump.
+	canalCfg.TimestampStringLocation = time.UTC
+	// DECIMAL values are read exactly both from the binlog and the dump.
+	canalCfg.UseDecimal = true
+
+	b.snapshotCfg = &snapshotConfig{
+		addr:        myCfg.Addr,
+		user:        myCfg.User,
+		password:    myCfg.Password,
+		gtidMode:    cfg.Replication.GTIDMode,
+		chunkSize:   myCfg.Dump.ChunkSize,
+		parallelism: myCfg.Dump.Parallelism,
+	}
+	if b.snapshotCfg.chunkSize <= 0 {
+		return fmt.Errorf("invalid dump chunk size: %d", b.snapshotCfg.chunkSize)
+	}
 
 	canalCfg.Dump.ExecutionPath = myCfg.Dump.ExecPath
+	switch myCfg.Dump.Mode {
+	case config.DumpModeMysqldump, "":
+	case config.DumpModeNative:
+		// Canal skips the dump without mysqldump, we make it on our own.
+		canalCfg.Dump.ExecutionPath = ""
+		b.snapshotCfg.native = true
+	default:
+		return fmt.Errorf("unsupported dump mode: %s", myCfg.Dump.Mode)
+	}
 	canalCfg.Dump.DiscardErr = false
 	canalCfg.Dump.SkipMasterData = myCfg.Dump.SkipMasterData
-	canalCfg.Dump.ExtraOptions = myCfg.Dump.ExtraOptions
+	// Canal dumps with --skip-tz-utc, TIMESTAMP values must be dumped in UTC
+	// as they are read from the binlog. The latter option wins.
+	// BIT, BINARY and spatial values are dumped as hex literals, canal decodes
+	// them to the bytes the binlog holds.
+	canalCfg.Dump.ExtraOptions = append([]string{"--tz-utc", "--hex-blob"}, myCfg.Dump.ExtraOptions...)
 
 	syncOnly := make([]string, 0, len(cfg.Replication.Mappings))
 	for _, mapping := range cfg.Replication.Mappings {
-		source := mapping.Source
-		regex := fmt.Sprintf("%s\\.%s", source.Schema, source.Table)
-		syncOnly = append(syncOnly, regex)
+		syncOnly = append(syncOnly, includeTableRegex(mapping))
 	}
 	canalCfg.IncludeTableRegex = syncOnly
 
@@ -209,7 +310,7 @@ func (b *Bridge) newCanal(cfg *config.Config) error {
 		return err
 	}
 
-	eH := newEventHandler(b, cfg.Replication.GTIDMode)
+	eH := newEventHandler(b, cfg.Replication.GTIDMode, cfg.Replication.MaxTxRows)
 	cn.SetEventHandler(eH)
 
 	b.canal = cn
@@ -217,11 +318,33 @@ func (b *Bridge) newCanal(cfg *config.Config) error {
 	return nil
 }
 
+// checkBinlogRowImage checks binlog row image for MySQL and MariaDB,
+// canal skips the check for MariaDB.
+func (b *Bridge) checkBinlogRowImage(flavor, image string) error {
+	if flavor != mysql.MariaDBFlavor {
+		return b.canal.CheckBinlogRowImage(image)
+	}
+
+	res, err := b.canal.Execute(`SHOW GLOBAL VARIABLES LIKE "binlog_row_image"`)
+	if err != nil {
+		return err
+	}
+
+	// MariaDB has binlog row image since 10.1.6, so older will return empty.
+	rowImage, _ := res.GetString(0, 1)
+	if rowImage != "" && !strings.EqualFold(rowImage, image) {
+		return fmt.Errorf("MariaDB uses %s binlog row image, but we want %s", rowImage, image)
+	}
+
+	return nil
+}
+
 func (b *Bridge) syncRulesAndCanalDump() {
 	var db string
 	dbs := map[string]struct{}{}
 	tables := make([]string, 0, len(b.rules))
-	for _, rule := range b.rules {
+	for _, rules := range b.tables() {
+		rule := rules[0]
 		db = rule.schema
 		dbs[rule.schema] = struct{}{}
 		tables = append(tables, rule.table)
@@ -288,17 +411,7 @@ func (b *Bridge) Run() <-chan error {
 		b.setRunning(true)
 	}()
 
-	var err error
-	pos := b.stateSaver.position()
-	switch p := pos.(type) {
-	case *gtidSet:
-		err = b.canal.StartFromGTID(p.pos)
-	case *binlogPos:
-		err = b.canal.RunFrom(p.pos)
-	default:
-		err = errors.New("unsupported master position: expected GTID set or binlog file position")
-	}
-
+	err := b.runCanal()
 	if err != nil {
 		errCh <- err
 	}
@@ -310,21 +423,159 @@ func (b *Bridge) Run() <-chan error {
 	return errCh
 }
 
+// runCanal makes the initial snapshot if required
+// and runs replication from the saved position.
+func (b *Bridge) runCanal() error {
+	pos := b.stateSaver.position()
+	if b.snapshotCfg.native && isEmptyPosition(pos) {
+		var err error
+		pos, err = b.snapshot()
+		if err != nil {
+			return err
+		}
+
+		// The position is saved right after the snapshot rows.
+		b.syncCh <- &savePos{
+			pos:   pos,
+			force: true,
+		}
+		b.syncCh <- &dumpDone{}
+	} else if b.snapshotCfg.native {
+		// The progress may remain if replicator stopped
+		// right after the snapshot position was saved.
+		if err := b.stateSaver.removeDump(); err != nil {
+			return err
+		}
+	}
+
+	switch p := pos.(type) {
+	case *gtidSet:
+		return b.canal.StartFromGTID(p.pos)
+	case *binlogPos:
+		return b.canal.RunFrom(p.pos)
+	default:
+		return errors.New("unsupported master position: expected GTID set or binlog file position")
+	}
+}
+
 func (b *Bridge) syncLoop() error {
+	// In exactly-once mode binlog transactions are collected until the next
+	// position and then applied together with it in one Tarantool transaction.
+	var pending []tnt.Query
+
+	// Changes of the spaces being resynced are deferred until the copy is done.
+	deferred := make(map[string][]*batch)
+
+	// Repairs which rows are read ahead of the synced position.
+	var repaired []*repairKeys
+
+	// The position the rows of the resumed dump are read at,
+	// the dump progress is removed once the position is saved past it.
+	var resumedUntil position
+	var dumpLeft bool
+
 	for {
 		select {
 		case got := <-b.syncCh:
+			var err error
 			switch v := got.(type) {
 			case *savePos:
-				err := b.stateSaver.save(v.pos, v.force)
-				if err != nil {
-					return err
+				repaired = dropRepaired(repaired, v.pos)
+				if resumedUntil != nil && v.pos.contains(resumedUntil) {
+					resumedUntil = nil
+				}
+				if len(repaired) > 0 || resumedUntil != nil {
+					// The position is saved when the binlog reaches the repaired
+					// or the resumed dump rows, so the preceding changes are never replayed over them.
+					// Meanwhile the collected changes are applied without the position.
+					if b.txSaver != nil {
+						err = b.doTransaction(pending)
+						pending = nil
+					}
+
+					break
+				}
+
+				if b.txSaver != nil {
+					err = b.txSaver.commit(pending, v.pos, v.force)
+					pending = nil
+				} else {
+					err = b.stateSaver.save(v.pos, v.force)
+				}
+				if err == nil && dumpLeft {
+					dumpLeft = false
+					err = b.stateSaver.removeDump()
+				}
+			case *transaction:
+				replaceRepaired(repaired, v.batches)
+				if resumedUntil != nil {
+					replaceInserts(v.batches)
+				}
+				deferBatches(deferred, v.batches)
+
+				switch {
+				case b.txSaver == nil:
+					err = b.doTransaction(v.queries())
+				case v.partial:
+					// The transaction is too large, so the collected changes
+					// are applied without the position.
+					err = b.doTransaction(append(pending, v.queries()...))
+					pending = nil
+				default:
+					pending = append(pending, v.queries()...)
 				}
 			case *batch:
-				err := b.doBatch(v)
-				if err != nil {
-					return err
+				replaceRepaired(repaired, []*batch{v})
+				if resumedUntil != nil {
+					replaceInserts([]*batch{v})
 				}
+				err = b.doBatch(v)
+			case *dumpChunk:
+				err = b.saveDumpProgress(v)
+			case *dumpResumed:
+				resumedUntil = v.until
+			case *dumpDone:
+				if resumedUntil != nil {
+					// The progress is kept, so the replicator restarted before
+					// the binlog reaches the resumed rows resumes the dump again.
+					dumpLeft = true
+
+					break
+				}
+				err = b.stateSaver.removeDump()
+			case *resyncStart:
+				// Pending changes must not be applied over the copied rows.
+				err = b.doTransaction(pending)
+				pending = nil
+				if err == nil {
+					deferred[v.space] = nil
+					err = b.truncateSpace(v.space)
+				}
+			case *spaceTruncate:
+				// Pending changes precede the truncate.
+				err = b.doTransaction(pending)
+				pending = nil
+				if err == nil {
+					if _, ok := deferred[v.space]; ok {
+						deferred[v.space] = nil
+					}
+					err = b.truncateSpace(v.space)
+				}
+			case *repairKeys:
+				repaired = append(repaired, v)
+				// Pending changes precede the repair.
+				err = b.doTransaction(append(pending, v.queries()...))
+				pending = nil
+				if err == nil {
+					b.logRepair(v)
+				}
+			case *resyncDone:
+				batches := deferred[v.space]
+				delete(deferred, v.space)
+				err = b.applyDeferred(batches)
+			}
+			if err != nil {
+				return err
 			}
 			b.syncedAt.Store(time.Now().Unix())
 		case <-b.ctx.Done():
@@ -333,17 +584,21 @@ func (b *Bridge) syncLoop() error {
 	}
 }
 
-func (b *Bridge) doBatch(req *batch) error {
-	var queries []tnt.Query
-	switch req.action {
-	case actionUpdate:
-		queries = makeUpdateQueries(req.reqs)
-	case actionInsert:
-		queries = makeInsertQueries(req.reqs)
-	case actionDelete:
-		queries = makeDeleteQueries(req.reqs)
+// saveDumpProgress updates the dump progress, the native snapshot
+// progress is saved to continue the snapshot after restart.
+func (b *Bridge) saveDumpProgress(chunk *dumpChunk) error {
+	progress := b.dump.update(chunk)
+	metrics.SetDumpProgress(chunk.table, progress.Rows, progress.Done)
+
+	if !b.snapshotCfg.native {
+		return nil
 	}
 
+	return b.stateSaver.saveDump(b.dump)
+}
+
+func (b *Bridge) doBatch(req *batch) error {
+	queries := makeQueries(req)
 	for _, q := range queries {
 		_, err := b.tntClient.Exec(context.Background(), q)
 		if err != nil {
@@ -358,6 +613,31 @@ func (b *Bridge) doBatch(req *batch) error {
 	return nil
 }
 
+func (b *Bridge) doTransaction(queries []tnt.Query) error {
+	if len(queries) == 0 {
+		return nil
+	}
+
+	ops, err := makeTxOps(queries)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.tntClient.Exec(context.Background(), &tnt.Eval{
+		Expression: applyTxExpr,
+		Tuple:      []interface{}{ops},
+	})
+	if err != nil {
+		b.logger.Err(err).
+			Int("queries", len(queries)).
+			Msg("could not apply tarantool transaction")
+
+		return err
+	}
+
+	return nil
+}
+
 func (b *Bridge) Close() error {
 	var err error
 
@@ -400,6 +680,11 @@ func (b *Bridge) Dumping() bool {
 	return b.dumping.Load()
 }
 
+// DumpProgress returns the progress of the initial dump.
+func (b *Bridge) DumpProgress() DumpProgress {
+	return b.dump.progress(len(b.tables()))
+}
+
 func (b *Bridge) runBackgroundJobs() {
 	go func() {
 		for range time.Tick(1 * time.Second) {
@@ -2,6 +2,7 @@ package bridge
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -132,6 +133,11 @@ func (s *bridgeSuite) AfterTest(_, _ string) {
 	})
 	assert.NoError(t, err)
 
+	_, err = s.executeTNT(&tarantool.Eval{
+		Expression: fmt.Sprintf("if box.space.%[1]s then box.space.%[1]s:truncate() end", s.cfg.App.State.Space),
+	})
+	assert.NoError(t, err)
+
 	_, err = s.executeSQL("TRUNCATE city.users")
 	assert.NoError(t, err)
 
@@ -151,6 +157,61 @@ func (s *bridgeSuite) TestNewBridge() {
 	s.bridge = b
 }
 
+func (s *bridgeSuite) TestNewBridge_IncompatibleSpace() {
+	cfg := *s.cfg
+	cfg.Replication.Mappings = nil
+	for _, mapping := range s.cfg.Replication.Mappings {
+		if mapping.Source.Table == "logins" {
+			// The space has 6 not nullable fields.
+			mapping.Source.Columns = []string{"attempts"}
+		}
+		cfg.Replication.Mappings = append(cfg.Replication.Mappings, mapping)
+	}
+
+	_, err := New(&cfg, s.logger)
+	require.Error(s.T(), err)
+	assert.Contains(s.T(), err.Error(), "no column is mapped")
+}
+
+func (s *bridgeSuite) TestNewBridge_CreateSpaces() {
+	t := s.T()
+
+	cfg := *s.cfg
+	cfg.Replication.ConnectionDest.CreateSpaces = true
+	cfg.Replication.Mappings = append([]config.Mapping(nil), s.cfg.Replication.Mappings...)
+
+	mapping := cfg.Replication.Mappings[0]
+	mapping.Dest.Space = "users_generated"
+	cfg.Replication.Mappings = append(cfg.Replication.Mappings, mapping)
+
+	defer func() {
+		_, err := s.executeTNT(&tarantool.Eval{
+			Expression: "if box.space.users_generated then box.space.users_generated:drop() end",
+		})
+		assert.NoError(t, err)
+	}()
+
+	s.init(&cfg)
+
+	count, err := s.countTuples("users_generated")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func (s *bridgeSuite) TestGenerateMappings() {
+	t := s.T()
+
+	mappings, err := GenerateMappings(s.cfg, "city", s.logger)
+	require.NoError(t, err)
+	require.Len(t, mappings, 2)
+
+	assert.Equal(t, "logins", mappings[0].Dest.Space)
+	assert.Equal(t, []string{"attempts", "longitude", "latitude"}, mappings[0].Source.Columns)
+	assert.Equal(t, "users", mappings[1].Dest.Space)
+	assert.Equal(t, []string{"username", "password", "name", "email"}, mappings[1].Source.Columns)
+	assert.Equal(t, "unsigned", mappings[1].Dest.Column["id"].Cast)
+}
+
 func (s *bridgeSuite) TestDump() {
 	t := s.T()
 	dumpPath := "/usr/bin/mysqldump"
@@ -198,6 +259,283 @@ func (s *bridgeSuite) TestDump() {
 	assert.False(t, s.bridge.Running())
 }
 
+func (s *bridgeSuite) TestNativeSnapshot() {
+	t := s.T()
+
+	tuples := 250
+
+	cfg := *s.cfg
+	cfg.Replication.ConnectionSrc.Dump.Mode = config.DumpModeNative
+	cfg.Replication.ConnectionSrc.Dump.ChunkSize = 100
+	s.init(&cfg)
+
+	for i := 0; i < tuples; i++ {
+		_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "bob", "12345", "Bob", "bob@email.com")
+		require.NoError(t, err)
+	}
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", uint64(tuples))
+	}, 1*time.Second, 50*time.Millisecond)
+
+	_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "alice", "12345", "Alice", "alice@email.com")
+	require.NoError(t, err)
+
+	err = s.bridge.canal.CatchMasterPos(500 * time.Millisecond)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", uint64(tuples+1))
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	assert.True(t, s.bridge.Running())
+
+	err = s.bridge.Close()
+	assert.NoError(t, err)
+}
+
+func (s *bridgeSuite) TestResync() {
+	t := s.T()
+	s.init(s.cfg)
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	require.Eventually(t, s.bridge.Running, 500*time.Millisecond, 50*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "bob", "12345", "Bob", "bob@email.com")
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 10)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	// Corrupt the space.
+	_, err := s.executeTNT(&tarantool.Delete{
+		Space: "users",
+		Key:   uint64(1),
+	})
+	require.NoError(t, err)
+
+	err = s.bridge.Resync("city", "unknown")
+	assert.True(t, errors.Is(err, ErrRuleNotExist))
+
+	err = s.bridge.Resync("city", "users")
+	require.NoError(t, err)
+
+	_, err = s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "alice", "12345", "Alice", "alice@email.com")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 11)
+	}, 1*time.Second, 50*time.Millisecond)
+
+	err = s.bridge.Close()
+	assert.NoError(t, err)
+}
+
+func (s *bridgeSuite) TestVerify() {
+	t := s.T()
+	s.init(s.cfg)
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	require.Eventually(t, s.bridge.Running, 500*time.Millisecond, 50*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "bob", "12345", "Bob", "bob@email.com")
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 10)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	err := s.bridge.Close()
+	require.NoError(t, err)
+
+	// The checksum computed in Tarantool matches the checksum of the synced tuples.
+	got, err := s.executeTNT(&tarantool.Select{
+		Space:    "users",
+		Iterator: tarantool.IterAll,
+	})
+	require.NoError(t, err)
+	require.Len(t, got.Data, 10)
+
+	keys := make([]interface{}, 0, len(got.Data))
+	for _, tuple := range got.Data {
+		keys = append(keys, tuple[:1])
+	}
+
+	rules, err := s.bridge.tableRules("city", "users")
+	require.NoError(t, err)
+	for _, r := range rules {
+		if r.space != "users" {
+			continue
+		}
+
+		equal, sumErr := s.bridge.equalRangeChecksum(context.Background(), r, got.Data, keys)
+		require.NoError(t, sumErr)
+		assert.True(t, equal)
+	}
+
+	_, err = s.executeTNT(&tarantool.Delete{
+		Space: "users",
+		Key:   uint64(1),
+	})
+	require.NoError(t, err)
+
+	_, err = s.executeTNT(&tarantool.Replace{
+		Space: "users",
+		Tuple: []interface{}{uint64(2), "alice", "12345", "bob@email.com"},
+	})
+	require.NoError(t, err)
+
+	_, err = s.executeTNT(&tarantool.Insert{
+		Space: "users",
+		Tuple: []interface{}{uint64(100), "bob", "12345", "bob@email.com"},
+	})
+	require.NoError(t, err)
+
+	reports, err := s.bridge.Verify(context.Background())
+	require.NoError(t, err)
+
+	var report *VerifyReport
+	for _, r := range reports {
+		if r.Table == "users" {
+			report = r
+		}
+	}
+	require.NotNil(t, report)
+
+	assert.EqualValues(t, 10, report.Rows)
+	assert.Equal(t, [][]interface{}{{uint64(1)}}, report.Missing)
+	assert.Equal(t, [][]interface{}{{uint64(2)}}, report.Differ)
+	require.Len(t, report.Extra, 1)
+	assert.EqualValues(t, 100, report.Extra[0][0])
+	assert.False(t, report.Consistent())
+}
+
+func (s *bridgeSuite) TestRepair() {
+	t := s.T()
+	s.init(s.cfg)
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	require.Eventually(t, s.bridge.Running, 500*time.Millisecond, 50*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "bob", "12345", "Bob", "bob@email.com")
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 10)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	_, err := s.executeTNT(&tarantool.Delete{
+		Space: "users",
+		Key:   uint64(1),
+	})
+	require.NoError(t, err)
+
+	_, err = s.executeTNT(&tarantool.Replace{
+		Space: "users",
+		Tuple: []interface{}{uint64(2), "alice", "12345", "bob@email.com"},
+	})
+	require.NoError(t, err)
+
+	_, err = s.executeTNT(&tarantool.Insert{
+		Space: "users",
+		Tuple: []interface{}{uint64(100), "bob", "12345", "bob@email.com"},
+	})
+	require.NoError(t, err)
+
+	err = s.bridge.StartVerify("city", "users", true)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		reports, err := s.bridge.Verify(context.Background())
+		if err != nil {
+			return false
+		}
+
+		for _, r := range reports {
+			if !r.Consistent() {
+				return false
+			}
+		}
+
+		return true
+	}, 1*time.Second, 100*time.Millisecond)
+
+	err = s.bridge.Close()
+	assert.NoError(t, err)
+}
+
+func (s *bridgeSuite) TestPatternMapping() {
+	t := s.T()
+
+	cfg := *s.cfg
+	cfg.Replication.Mappings = nil
+	for _, mapping := range s.cfg.Replication.Mappings {
+		if mapping.Source.Table == "users" {
+			mapping.Source.Schema = "ci(ty)"
+			mapping.Source.Table = "user[s]"
+			mapping.Source.Regex = true
+		}
+		cfg.Replication.Mappings = append(cfg.Replication.Mappings, mapping)
+	}
+	s.init(&cfg)
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	require.Eventually(t, s.bridge.Running, 500*time.Millisecond, 50*time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "bob", "12345", "Bob", "bob@email.com")
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 10)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	err := s.bridge.Resync("city", "users")
+	assert.True(t, errors.Is(err, ErrSharedSpace))
+
+	err = s.bridge.Close()
+	assert.NoError(t, err)
+}
+
 func (s *bridgeSuite) TestReplication() {
 	t := s.T()
 
@@ -481,3 +819,192 @@ func (s *bridgeSuite) TestRenameColumn() {
 	err = s.bridge.Close()
 	assert.NoError(t, err)
 }
+
+func (s *bridgeSuite) TestAddColumnFirst() {
+	t := s.T()
+
+	s.init(s.cfg)
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	<-s.bridge.canal.WaitDumpDone()
+
+	_, err := s.executeSQL("ALTER TABLE city.users ADD COLUMN `nickname` varchar(50) FIRST")
+	require.NoError(t, err)
+
+	defer func() {
+		_, err = s.executeSQL("ALTER TABLE city.users DROP COLUMN `nickname`")
+		require.NoError(t, err)
+	}()
+
+	_, err = s.executeSQL("INSERT INTO city.users (id, nickname, username, password, name, email) VALUES (?, ?, ?, ?, ?, ?)", 1, "al", "alice", "123", "Alice", "alice@email.com")
+	require.NoError(t, err)
+
+	err = s.bridge.canal.CatchMasterPos(500 * time.Millisecond)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 1)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	got, err := s.executeTNT(&tarantool.Select{
+		Space: "users",
+		Key:   1,
+	})
+	require.NoError(t, err)
+	require.Len(t, got.Data, 1)
+	want := []interface{}{1, "alice", "123", "alice@email.com"}
+	gotTuple := got.Data[0]
+	require.Len(t, gotTuple, len(want))
+	for i, v := range want {
+		require.EqualValues(t, v, gotTuple[i])
+	}
+
+	err = s.bridge.Close()
+	assert.NoError(t, err)
+}
+
+func (s *bridgeSuite) TestTruncateTable() {
+	t := s.T()
+
+	s.init(s.cfg)
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	<-s.bridge.canal.WaitDumpDone()
+
+	for i := 0; i < 10; i++ {
+		_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "bob", "12345", "Bob", "bob@email.com")
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 10)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	_, err := s.executeSQL("TRUNCATE TABLE city.users")
+	require.NoError(t, err)
+
+	_, err = s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "alice", "12345", "Alice", "alice@email.com")
+	require.NoError(t, err)
+
+	err = s.bridge.canal.CatchMasterPos(500 * time.Millisecond)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 1)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	err = s.bridge.Close()
+	assert.NoError(t, err)
+}
+
+func (s *bridgeSuite) TestWhereFilter() {
+	t := s.T()
+
+	cfg := *s.cfg
+	cfg.Replication.Mappings = make([]config.Mapping, len(s.cfg.Replication.Mappings))
+	copy(cfg.Replication.Mappings, s.cfg.Replication.Mappings)
+	cfg.Replication.Mappings[0].Source.Where = "username != 'robot'"
+
+	_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?), (?, ?, ?, ?)",
+		"bob", "12345", "Bob", "bob@email.com", "robot", "12345", "Robot", "robot@email.com")
+	require.NoError(t, err)
+
+	s.init(&cfg)
+
+	go func() {
+		errors := s.bridge.Run()
+		for err := range errors {
+			assert.NoError(t, err)
+		}
+	}()
+
+	<-s.bridge.canal.WaitDumpDone()
+
+	require.Eventually(t, func() bool {
+		return s.hasSyncedData("users", 1)
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	// The row moved into the filter is inserted, moved out of it is deleted.
+	_, err = s.executeSQL("UPDATE city.users SET username = 'alice' WHERE username = 'robot'")
+	require.NoError(t, err)
+	_, err = s.executeSQL("UPDATE city.users SET username = 'robot' WHERE username = 'bob'")
+	require.NoError(t, err)
+	_, err = s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", "robot", "12345", "Robot", "robot@email.com")
+	require.NoError(t, err)
+
+	err = s.bridge.canal.CatchMasterPos(500 * time.Millisecond)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		res, err := s.executeTNT(&tarantool.Select{
+			Space:    "users",
+			Iterator: tarantool.IterAll,
+		})
+		if err != nil || len(res.Data) != 1 {
+			return false
+		}
+
+		return res.Data[0][1] == "alice"
+	}, 500*time.Millisecond, 50*time.Millisecond)
+
+	err = s.bridge.Close()
+	assert.NoError(t, err)
+}
+
+func (s *bridgeSuite) TestExactlyOnce() {
+	t := s.T()
+
+	cfg := *s.cfg
+	cfg.App.State.Storage = config.StateStorageTarantool
+	cfg.Replication.ExactlyOnce = true
+
+	for i := 1; i <= 3; i++ {
+		s.init(&cfg)
+		require.NotNil(t, s.bridge.txSaver)
+
+		go func() {
+			errors := s.bridge.Run()
+			for err := range errors {
+				assert.NoError(t, err)
+			}
+		}()
+
+		name := fmt.Sprintf("robot_%d", i)
+		_, err := s.executeSQL("INSERT INTO city.users (username, password, name, email) VALUES (?, ?, ?, ?)", name, "12345", name, "robot@email.com")
+		require.NoError(t, err)
+
+		err = s.bridge.canal.CatchMasterPos(500 * time.Millisecond)
+		require.NoError(t, err)
+
+		wantTuples := uint64(i)
+		require.Eventually(t, func() bool {
+			return s.hasSyncedData("users", wantTuples)
+		}, 500*time.Millisecond, 50*time.Millisecond)
+
+		require.Eventually(t, s.hasSyncedPos, 500*time.Millisecond, 50*time.Millisecond)
+
+		err = s.bridge.Close()
+		assert.NoError(t, err)
+	}
+}
+
+func (s *bridgeSuite) TestExactlyOnce_RequiresTarantoolState() {
+	cfg := *s.cfg
+	cfg.App.State.Storage = config.StateStorageFile
+	cfg.Replication.ExactlyOnce = true
+
+	_, err := New(&cfg, s.logger)
+	assert.Error(s.T(), err)
+}
@@ -1,6 +1,11 @@
 package bridge
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/siddontang/go-mysql/canal"
+	tnt "github.com/viciious/go-tarantool"
+)
 
 type action string
 
@@ -10,53 +15,116 @@ const (
 	actionDelete action = "delete"
 )
 
+type conflictPolicy int
+
+const (
+	conflictError   conflictPolicy = iota // fail on inserting an existing tuple
+	conflictReplace                       // replace the existing tuple by the new one
+	conflictUpsert                        // update fields of the existing tuple
+	conflictSkip                          // keep the existing tuple as is
+)
+
+func conflictPolicyFromString(str string) (conflictPolicy, error) {
+	switch str {
+	case "", "error":
+		return conflictError, nil
+	case "replace":
+		return conflictReplace, nil
+	case "upsert":
+		return conflictUpsert, nil
+	case "skip":
+		return conflictSkip, nil
+	default:
+		return conflictError, fmt.Errorf("unknown conflict policy: %s", str)
+	}
+}
+
 type reqArg struct {
 	field uint64
 	value interface{}
 }
 
 type request struct {
-	action action
-	space  string
-	keys   []reqArg
-	args   []reqArg
+	action     action
+	space      string
+	keys       []reqArg
+	args       []reqArg
+	onConflict conflictPolicy
 }
 
 type batch struct {
 	action action
 	reqs   []*request
+	dump   bool // rows are read from the dump, not from binlog
 }
 
-func makeInsertRequest(r *rule, row []interface{}) (*request, error) {
-	keys := make([]reqArg, 0, len(r.pks))
-	for _, pk := range r.pks {
-		value, err := pk.fetchValue(row)
-		if err != nil {
-			return nil, err
-		}
-		keys = append(keys, reqArg{
-			field: pk.tupIndex,
-			value: value,
-		})
+// transaction groups the batches of one MySQL transaction.
+type transaction struct {
+	batches []*batch
+	partial bool // the transaction is too large and split into parts
+}
+
+func (t *transaction) queries() []tnt.Query {
+	queries := make([]tnt.Query, 0, len(t.batches))
+	for _, b := range t.batches {
+		queries = append(queries, makeQueries(b)...)
 	}
 
-	args := make([]reqArg, 0, len(r.attrs))
-	for _, attr := range r.attrs {
-		value, err := attr.fetchValue(row)
-		if err != nil {
-			return nil, err
+	return queries
+}
+
+// makeRequests makes the requests of the rows event action.
+func makeRequests(r *rule, rowsAction string, rows [][]interface{}) ([]*request, error) {
+	switch rowsAction {
+	case canal.InsertAction:
+		return makeInsertBatch(r, rows)
+	case canal.DeleteAction:
+		return makeDeleteBatch(r, rows)
+	case canal.UpdateAction:
+		return makeUpdateRequests(r, rows)
+	}
+
+	return nil, fmt.Errorf("invalid rows action: %s", rowsAction)
+}
+
+// makeArgs fetches the values of the attributes from the row,
+// ok is false if the row is skipped by on_invalid policy.
+func makeArgs(r *rule, attrs []*attribute, row []interface{}) ([]reqArg, bool, error) {
+	args := make([]reqArg, 0, len(attrs))
+	for _, attr := range attrs {
+		value, ok, err := r.fetchValue(attr, row)
+		if err != nil || !ok {
+			return nil, false, err
 		}
+
 		args = append(args, reqArg{
 			field: attr.tupIndex,
 			value: value,
 		})
 	}
 
+	return args, true, nil
+}
+
+// makeInsertRequest makes the request inserting the row,
+// nil if the row is skipped by on_invalid policy.
+func makeInsertRequest(r *rule, row []interface{}) (*request, error) {
+	keys, ok, err := makeArgs(r, r.pks, row)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	args, ok, err := makeArgs(r, r.attrs, row)
+	if err != nil || !ok {
+		return nil, err
+	}
+
 	return &request{
-		action: actionInsert,
-		space:  r.space,
-		keys:   keys,
-		args:   args,
+		action:     actionInsert,
+		space:      r.space,
+		keys:       keys,
+		args:       args,
+		onConflict: r.onConflict,
 	}, nil
 }
 
@@ -64,12 +132,18 @@ func makeInsertBatch(r *rule, rows [][]interface{}) ([]*request, error) {
 	reqs := make([]*request, 0, len(rows))
 
 	for _, row := range rows {
+		if !r.match(row) {
+			continue
+		}
+
 		req, err := makeInsertRequest(r, row)
 		if err != nil {
 			return nil, err
 		}
 
-		reqs = append(reqs, req)
+		if req != nil {
+			reqs = append(reqs, req)
+		}
 	}
 
 	return reqs, nil
@@ -85,21 +159,63 @@ func makeUpdateRequests(r *rule, rows [][]interface{}) ([]*request, error) {
 		before := rows[i]
 		after := rows[i+1]
 
-		// In Tarantool it is illegal to modify a primary-key field.
-		// So we make two requests: delete and insert instead of update.
-		isPKChanged := false
-		for _, pk := range r.pks {
-			pkBefore, err := pk.fetchValue(before)
+		// The row moved in or out of the filter is inserted or deleted.
+		matchBefore, matchAfter := r.match(before), r.match(after)
+		switch {
+		case !matchBefore && !matchAfter:
+			continue
+		case !matchAfter:
+			req, err := makeDeleteRequest(r, before)
 			if err != nil {
 				return nil, err
 			}
 
-			pkAfter, err := pk.fetchValue(after)
+			if req != nil {
+				reqs = append(reqs, req)
+			}
+
+			continue
+		case !matchBefore:
+			req, err := makeInsertRequest(r, after)
 			if err != nil {
 				return nil, err
 			}
 
-			if pkBefore != pkAfter {
+			if req != nil {
+				reqs = append(reqs, req)
+			}
+
+			continue
+		}
+
+		keys, ok, err := makeArgs(r, r.pks, before)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		keysAfter, ok, err := makeArgs(r, r.pks, after)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// The row is skipped under the new key, the tuple of the old key is stale.
+			reqs = append(reqs, &request{
+				action: actionDelete,
+				space:  r.space,
+				keys:   keys,
+			})
+
+			continue
+		}
+
+		// In Tarantool it is illegal to modify a primary-key field.
+		// So we make two requests: delete and insert instead of update.
+		isPKChanged := false
+		for j := range keys {
+			if keyString([]interface{}{keys[j].value}) != keyString([]interface{}{keysAfter[j].value}) {
 				isPKChanged = true
 
 				break
@@ -107,52 +223,48 @@ func makeUpdateRequests(r *rule, rows [][]interface{}) ([]*request, error) {
 		}
 
 		if isPKChanged {
-			reqDel, err := makeDeleteRequest(r, before)
-			if err != nil {
-				return nil, err
-			}
 			reqInsert, err := makeInsertRequest(r, after)
 			if err != nil {
 				return nil, err
 			}
 
-			reqs = append(reqs, reqDel, reqInsert)
+			// The tuple of the old key is deleted even if the new row is skipped.
+			reqDel := &request{
+				action: actionDelete,
+				space:  r.space,
+				keys:   keys,
+			}
+
+			reqs = append(reqs, reqDel)
+			if reqInsert != nil {
+				reqs = append(reqs, reqInsert)
+			}
 
 			continue
 		}
 
 		// Normal flow: update non-primary fields.
-		keys := make([]reqArg, 0, len(r.pks))
-		args := make([]reqArg, 0, len(r.attrs))
-
-		for _, pk := range r.pks {
-			value, err := pk.fetchValue(before)
-			if err != nil {
-				return nil, err
-			}
-
-			keys = append(keys, reqArg{
-				field: pk.tupIndex,
-				value: value,
-			})
+		args, ok, err := makeArgs(r, r.attrs, after)
+		if err != nil {
+			return nil, err
 		}
-
-		for _, attr := range r.attrs {
-			value, err := attr.fetchValue(after)
-			if err != nil {
-				return nil, err
-			}
-			args = append(args, reqArg{
-				field: attr.tupIndex,
-				value: value,
+		if !ok {
+			// The row is skipped, the tuple holding its old values is stale.
+			reqs = append(reqs, &request{
+				action: actionDelete,
+				space:  r.space,
+				keys:   keys,
 			})
+
+			continue
 		}
 
 		req := &request{
-			action: actionUpdate,
-			space:  r.space,
-			keys:   keys,
-			args:   args,
+			action:     actionUpdate,
+			space:      r.space,
+			keys:       keys,
+			args:       args,
+			onConflict: r.onConflict,
 		}
 
 		reqs = append(reqs, req)
@@ -161,16 +273,29 @@ func makeUpdateRequests(r *rule, rows [][]interface{}) ([]*request, error) {
 	return reqs, nil
 }
 
+// makeDeleteRequest makes the request deleting the row,
+// nil if the row is skipped by on_invalid policy.
 func makeDeleteRequest(r *rule, row []interface{}) (*request, error) {
+	keys, ok, err := makeArgs(r, r.pks, row)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &request{
+		action: actionDelete,
+		space:  r.space,
+		keys:   keys,
+	}, nil
+}
+
+// makeDeleteRequestByKey makes the request to delete the tuple
+// by the primary key values already converted by the rule.
+func makeDeleteRequestByKey(r *rule, key []interface{}) *request {
 	keys := make([]reqArg, 0, len(r.pks))
-	for _, pk := range r.pks {
-		value, err := pk.fetchValue(row)
-		if err != nil {
-			return nil, err
-		}
+	for i, pk := range r.pks {
 		keys = append(keys, reqArg{
 			field: pk.tupIndex,
-			value: value,
+			value: key[i],
 		})
 	}
 
@@ -178,32 +303,25 @@ func makeDeleteRequest(r *rule, row []interface{}) (*request, error) {
 		action: actionDelete,
 		space:  r.space,
 		keys:   keys,
-	}, nil
+	}
 }
 
 func makeDeleteBatch(r *rule, rows [][]interface{}) ([]*request, error) {
 	reqs := make([]*request, 0, len(rows))
 
 	for _, row := range rows {
-		keys := make([]reqArg, 0, len(r.pks))
-		for _, pk := range r.pks {
-			value, err := pk.fetchValue(row)
-			if err != nil {
-				return nil, err
-			}
-			keys = append(keys, reqArg{
-				field: pk.tupIndex,
-				value: value,
-			})
+		if !r.match(row) {
+			continue
 		}
 
-		req := &request{
-			action: actionDelete,
-			space:  r.space,
-			keys:   keys,
+		req, err := makeDeleteRequest(r, row)
+		if err != nil {
+			return nil, err
 		}
 
-		reqs = append(reqs, req)
+		if req != nil {
+			reqs = append(reqs, req)
+		}
 	}
 
 	return reqs, nil
@@ -3,7 +3,11 @@ package bridge
 import (
 	"testing"
 
+	"github.com/siddontang/go-mysql/canal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
 )
 
 func Test_makeInsertRequest(t *testing.T) {
@@ -610,3 +614,241 @@ func Test_makeDeleteRequest(t *testing.T) {
 		})
 	}
 }
+
+func Test_conflictPolicyFromString(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    conflictPolicy
+		wantErr bool
+	}{
+		{str: "", want: conflictError},
+		{str: "error", want: conflictError},
+		{str: "replace", want: conflictReplace},
+		{str: "upsert", want: conflictUpsert},
+		{str: "skip", want: conflictSkip},
+		{str: "ignore", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := conflictPolicyFromString(tt.str)
+		if tt.wantErr {
+			assert.Error(t, err)
+
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func Test_makeDeleteRequestByKey(t *testing.T) {
+	r := &rule{
+		schema: "city",
+		table:  "logins",
+		pks: []*attribute{
+			{
+				colIndex: 0,
+				tupIndex: 0,
+				name:     "user_id",
+				vType:    typeNumber,
+				unsigned: true,
+			},
+			{
+				colIndex: 1,
+				tupIndex: 1,
+				name:     "user_ip",
+				vType:    typeString,
+			},
+		},
+		space: "logins",
+	}
+
+	got := makeDeleteRequestByKey(r, []interface{}{uint64(1), "10.20.10.1"})
+
+	assert.Equal(t, &request{
+		action: actionDelete,
+		space:  "logins",
+		keys: []reqArg{
+			{
+				field: 0,
+				value: uint64(1),
+			},
+			{
+				field: 1,
+				value: "10.20.10.1",
+			},
+		},
+	}, got)
+}
+
+func Test_makeRequests_Filter(t *testing.T) {
+	mapping := newTestMapping("users", []string{"username", "email"}, nil)
+	mapping.Source.Where = "email IS NOT NULL"
+
+	r, err := newRule(mapping, newTestUsersTable())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		action      string
+		rows        [][]interface{}
+		wantActions []action
+	}{
+		{
+			name:        "InsertMatched",
+			action:      canal.InsertAction,
+			rows:        [][]interface{}{{1, "bob", "bob@example.com"}, {2, "alice", nil}},
+			wantActions: []action{actionInsert},
+		},
+		{
+			name:        "DeleteMatched",
+			action:      canal.DeleteAction,
+			rows:        [][]interface{}{{1, "bob", nil}, {2, "alice", "alice@example.com"}},
+			wantActions: []action{actionDelete},
+		},
+		{
+			name:   "UpdateMatched",
+			action: canal.UpdateAction,
+			rows: [][]interface{}{
+				{1, "bob", "bob@example.com"},
+				{1, "bob", "bob@example.org"},
+			},
+			wantActions: []action{actionUpdate},
+		},
+		{
+			name:   "UpdateOut",
+			action: canal.UpdateAction,
+			rows: [][]interface{}{
+				{1, "bob", "bob@example.com"},
+				{1, "bob", nil},
+			},
+			wantActions: []action{actionDelete},
+		},
+		{
+			name:   "UpdateIn",
+			action: canal.UpdateAction,
+			rows: [][]interface{}{
+				{1, "bob", nil},
+				{1, "bob", "bob@example.com"},
+			},
+			wantActions: []action{actionInsert},
+		},
+		{
+			name:   "UpdateNotMatched",
+			action: canal.UpdateAction,
+			rows: [][]interface{}{
+				{1, "bob", nil},
+				{1, "robert", nil},
+			},
+			wantActions: []action{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, err := makeRequests(r, tt.action, tt.rows)
+			require.NoError(t, err)
+
+			actions := make([]action, 0, len(reqs))
+			for _, req := range reqs {
+				actions = append(actions, req.action)
+			}
+			assert.Equal(t, tt.wantActions, actions)
+		})
+	}
+}
+
+func Test_makeRequests_SkipInvalid(t *testing.T) {
+	mapping := newTestMapping("users", []string{"username", "email"}, nil)
+	mapping.Dest.Column = map[string]config.MappingColumn{
+		"username": {Cast: "integer", OnInvalid: "log"},
+	}
+
+	r, err := newRule(mapping, newTestUsersTable())
+	require.NoError(t, err)
+
+	reqs, err := makeRequests(r, canal.InsertAction, [][]interface{}{
+		{1, "bob", "bob@example.com"},
+		{2, "42", "robot@example.com"},
+	})
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, uint64(2), reqs[0].keys[0].value)
+	assert.Equal(t, int64(42), reqs[0].args[0].value)
+
+	// The update of the row with the invalid value is skipped too,
+	// the tuple of its old values is deleted.
+	reqs, err = makeRequests(r, canal.UpdateAction, [][]interface{}{
+		{2, "42", "robot@example.com"},
+		{2, "bob", "robot@example.com"},
+	})
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, actionDelete, reqs[0].action)
+	assert.Equal(t, uint64(2), reqs[0].keys[0].value)
+}
+
+func Test_makeUpdateRequests_SkipInvalidNewKey(t *testing.T) {
+	mapping := newTestMapping("users", []string{"username", "email"}, nil)
+	mapping.Dest.Column = map[string]config.MappingColumn{
+		"id":       {Cast: "integer", OnInvalid: "log"},
+		"username": {Cast: "integer", OnInvalid: "log"},
+	}
+
+	table := newTestUsersTable()
+	table.Columns = nil
+	table.AddColumn("id", "varchar(36)", "", "")
+	table.AddColumn("username", "varchar(255)", "", "")
+	table.AddColumn("email", "varchar(255)", "", "")
+
+	r, err := newRule(mapping, table)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		rows [][]interface{}
+		want []*request
+	}{
+		{
+			name: "InvalidKey",
+			rows: [][]interface{}{
+				{"1", "42", "bob@example.com"},
+				{"bob", "42", "bob@example.com"},
+			},
+			want: []*request{
+				{action: actionDelete, space: "users", keys: []reqArg{{field: 0, value: int64(1)}}},
+			},
+		},
+		{
+			name: "InvalidValue",
+			rows: [][]interface{}{
+				{"1", "42", "bob@example.com"},
+				{"2", "bob", "bob@example.com"},
+			},
+			want: []*request{
+				{action: actionDelete, space: "users", keys: []reqArg{{field: 0, value: int64(1)}}},
+			},
+		},
+		{
+			name: "InvalidValueSameKey",
+			rows: [][]interface{}{
+				{"1", "42", "bob@example.com"},
+				{"1", "bob", "bob@example.com"},
+			},
+			want: []*request{
+				{action: actionDelete, space: "users", keys: []reqArg{{field: 0, value: int64(1)}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := makeRequests(r, canal.UpdateAction, tt.rows)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tnt "github.com/viciious/go-tarantool"
+)
+
+var (
+	ErrNotRunning       = errors.New("replication is not running")
+	ErrResyncInProgress = errors.New("resync is in progress")
+)
+
+const truncateSpaceExpr = `
+local name = ...
+local space = box.space[name]
+if space == nil then
+    box.error(box.error.NO_SUCH_SPACE, name)
+end
+space:truncate()
+`
+
+// resyncStart is sent to sync before the table is copied again:
+// the space is truncated and the live changes of the space are deferred.
+type resyncStart struct {
+	space string
+}
+
+// resyncDone is sent to sync after the copied rows,
+// the deferred changes are applied on top of them.
+type resyncDone struct {
+	space string
+}
+
+// Resync truncates the spaces of the table mappings and copies the table again.
+// Replication of other tables continues while the table is copied.
+// The spaces shared with other tables can not be resynced.
+//
+// Resync runs in background, returns error if it can not be started.
+func (b *Bridge) Resync(schema, table string) error {
+	if !b.Running() {
+		return ErrNotRunning
+	}
+
+	rules, err := b.tableRules(schema, table)
+	if err != nil {
+		return err
+	}
+
+	// Truncating the space would remove the tuples of other tables.
+	for _, r := range rules {
+		if b.sharedSpace(r.space) {
+			return fmt.Errorf("could not resync %s.%s: %w", schema, table, ErrSharedSpace)
+		}
+	}
+
+	key := ruleKey(schema, table)
+
+	if _, loaded := b.resyncs.LoadOrStore(key, struct{}{}); loaded {
+		return ErrResyncInProgress
+	}
+
+	go func() {
+		defer b.resyncs.Delete(key)
+
+		err := b.resync(rules)
+		if err != nil {
+			b.logger.Err(err).
+				Str("schema", schema).
+				Str("table", table).
+				Msg("resync failed")
+		}
+	}()
+
+	return nil
+}
+
+func (b *Bridge) resync(rules []*rule) error {
+	conn, err := b.connectSource()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	table := rules[0].tableInfo
+	spaces := make([]string, 0, len(rules))
+	for _, r := range rules {
+		spaces = append(spaces, r.space)
+	}
+
+	b.logger.Info().
+		Str("schema", table.Schema).
+		Str("table", table.Name).
+		Strs("spaces", spaces).
+		Msg("start resync")
+
+	// Changes committed after the start are deferred,
+	// so the copied rows never overwrite the newer ones.
+	for _, space := range spaces {
+		b.syncCh <- &resyncStart{
+			space: space,
+		}
+	}
+
+	total := 0
+	err = b.copyTable(conn, rules, nil, func(_ []interface{}, rows int, _ bool) {
+		total += rows
+	})
+
+	// The deferred changes are applied even if the copy is failed,
+	// otherwise the spaces stop receiving changes.
+	for _, space := range spaces {
+		b.syncCh <- &resyncDone{
+			space: space,
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	b.logger.Info().
+		Str("schema", table.Schema).
+		Str("table", table.Name).
+		Strs("spaces", spaces).
+		Int("rows", total).
+		Msg("resync done")
+
+	return nil
+}
+
+func (b *Bridge) truncateSpace(space string) error {
+	_, err := b.tntClient.Exec(context.Background(), &tnt.Eval{
+		Expression: truncateSpaceExpr,
+		Tuple:      []interface{}{space},
+	})
+	if err != nil {
+		return fmt.Errorf("could not truncate space %s: %w", space, err)
+	}
+
+	return nil
+}
+
+// deferBatches moves the requests of the resynced spaces
+// from the batches to the deferred ones.
+func deferBatches(deferred map[string][]*batch, batches []*batch) {
+	if len(deferred) == 0 {
+		return
+	}
+
+	for _, bt := range batches {
+		var moved map[string]*batch
+
+		reqs := bt.reqs[:0]
+		for _, req := range bt.reqs {
+			if _, ok := deferred[req.space]; !ok {
+				reqs = append(reqs, req)
+
+				continue
+			}
+
+			if moved == nil {
+				moved = make(map[string]*batch)
+			}
+
+			d, ok := moved[req.space]
+			if !ok {
+				d = &batch{
+					action: bt.action,
+				}
+				moved[req.space] = d
+				deferred[req.space] = append(deferred[req.space], d)
+			}
+
+			d.reqs = append(d.reqs, req)
+		}
+
+		bt.reqs = reqs
+	}
+}
+
+// applyDeferred applies the changes deferred during resync,
+// they may be already copied, so the tuples are replaced.
+func (b *Bridge) applyDeferred(batches []*batch) error {
+	for _, bt := range batches {
+		for _, req := range bt.reqs {
+			req.onConflict = conflictReplace
+		}
+
+		if err := b.doBatch(bt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
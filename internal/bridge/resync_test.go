@@ -0,0 +1,58 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_deferBatches(t *testing.T) {
+	deferred := map[string][]*batch{
+		"users": nil,
+	}
+
+	batches := []*batch{
+		{
+			action: actionInsert,
+			reqs: []*request{
+				{action: actionInsert, space: "users"},
+				{action: actionInsert, space: "logins"},
+			},
+		},
+		{
+			action: actionDelete,
+			reqs: []*request{
+				{action: actionDelete, space: "users"},
+			},
+		},
+	}
+
+	deferBatches(deferred, batches)
+
+	require.Len(t, batches[0].reqs, 1)
+	assert.Equal(t, "logins", batches[0].reqs[0].space)
+	assert.Empty(t, batches[1].reqs)
+
+	require.Len(t, deferred["users"], 2)
+	assert.Equal(t, actionInsert, deferred["users"][0].action)
+	assert.Len(t, deferred["users"][0].reqs, 1)
+	assert.Equal(t, actionDelete, deferred["users"][1].action)
+	assert.Len(t, deferred["users"][1].reqs, 1)
+	assert.NotContains(t, deferred, "logins")
+}
+
+func Test_deferBatches_NoResync(t *testing.T) {
+	batches := []*batch{
+		{
+			action: actionInsert,
+			reqs: []*request{
+				{action: actionInsert, space: "users"},
+			},
+		},
+	}
+
+	deferBatches(map[string][]*batch{}, batches)
+
+	assert.Len(t, batches[0].reqs, 1)
+}
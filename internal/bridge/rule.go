@@ -1,22 +1,57 @@
 package bridge
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 
+	"github.com/rs/zerolog"
 	"github.com/siddontang/go-mysql/schema"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+	"github.com/pparshin/go-mysql-tarantool/internal/metrics"
 )
 
 type rule struct {
 	schema string
 	table  string
-	pks    []*attribute // primary keys
+	pks    []*attribute // primary keys of the tuple
 	attrs  []*attribute // mapping attributes except primary keys
+	filter *rowFilter   // nil if all the rows are replicated
+
+	space          string
+	onConflict     conflictPolicy
+	onSchemaChange schemaChangePolicy
+	onTruncate     truncatePolicy
+	onDrop         dropPolicy
+	paused         bool // mapped columns or the table are dropped, the changes are skipped
 
-	space string
+	logger *zerolog.Logger // logs the invalid values, nil if they are not logged
 
 	tableInfo *schema.Table
 }
 
+type schemaChangePolicy int
+
+const (
+	schemaChangeFail      schemaChangePolicy = iota // stop the replication
+	schemaChangePause                               // skip the changes of the table
+	schemaChangeDropField                           // replicate the dropped columns as null
+)
+
+func schemaChangePolicyFromString(str string) (schemaChangePolicy, error) {
+	switch str {
+	case "", "fail":
+		return schemaChangeFail, nil
+	case "pause":
+		return schemaChangePause, nil
+	case "drop_field":
+		return schemaChangeDropField, nil
+	default:
+		return schemaChangeFail, fmt.Errorf("unknown schema change policy: %s", str)
+	}
+}
+
 func ruleKey(db, table string) string {
 	var sb strings.Builder
 	sb.Grow(len(db) + len(table) + 1)
@@ -26,3 +61,342 @@ func ruleKey(db, table string) string {
 
 	return sb.String()
 }
+
+// newRule compiles the mapping of the table.
+// The tuple starts with the key columns followed by the mapped columns.
+func newRule(mapping config.Mapping, tableInfo *schema.Table) (*rule, error) {
+	source := mapping.Source
+	colmap := mapping.Dest.Column
+
+	var pks []*attribute
+	if len(mapping.Dest.Key) == 0 {
+		pks = newAttrsFromPKs(tableInfo)
+	} else {
+		pks = make([]*attribute, 0, len(mapping.Dest.Key))
+		for i, name := range mapping.Dest.Key {
+			pk, err := newAttr(tableInfo, uint64(i), name)
+			if err != nil {
+				return nil, err
+			}
+
+			pks = append(pks, pk)
+		}
+	}
+	if len(pks) == 0 {
+		return nil, fmt.Errorf("no primary keys found, schema: %s, table: %s", tableInfo.Schema, tableInfo.Name)
+	}
+	for _, pk := range pks {
+		if m, ok := colmap[pk.name]; ok {
+			if err := applyColumnMapping(pk, m); err != nil {
+				return nil, err
+			}
+			if len(m.Extract) > 0 {
+				return nil, fmt.Errorf("JSON paths of key column %s can not be extracted", pk.name)
+			}
+		}
+	}
+
+	attrs := make([]*attribute, 0, len(source.Columns))
+	for _, name := range source.Columns {
+		isPK := false
+		for _, pk := range pks {
+			if name == pk.name {
+				isPK = true
+
+				break
+			}
+		}
+
+		if !isPK {
+			tupIndex := uint64(len(pks) + len(attrs))
+			attr, err := newAttr(tableInfo, tupIndex, name)
+			if err != nil {
+				return nil, err
+			}
+
+			var extracted []*attribute
+			if m, ok := colmap[name]; ok {
+				if err := applyColumnMapping(attr, m); err != nil {
+					return nil, err
+				}
+
+				extracted, err = newExtractedAttrs(attr, m.Extract)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			attrs = append(attrs, attr)
+			attrs = append(attrs, extracted...)
+		}
+	}
+
+	var filter *rowFilter
+	if source.Where != "" {
+		var err error
+		filter, err = newRowFilter(source.Where, tableInfo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid where of %s.%s: %w", tableInfo.Schema, tableInfo.Name, err)
+		}
+	}
+
+	onConflict, err := conflictPolicyFromString(mapping.Dest.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	onSchemaChange, err := schemaChangePolicyFromString(mapping.Dest.OnSchemaChange)
+	if err != nil {
+		return nil, err
+	}
+
+	onTruncate, err := truncatePolicyFromString(mapping.Dest.OnTruncate)
+	if err != nil {
+		return nil, err
+	}
+
+	onDrop, err := dropPolicyFromString(mapping.Dest.OnDrop)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rule{
+		schema:         tableInfo.Schema,
+		table:          tableInfo.Name,
+		pks:            pks,
+		attrs:          attrs,
+		filter:         filter,
+		space:          mapping.Dest.Space,
+		onConflict:     onConflict,
+		onSchemaChange: onSchemaChange,
+		onTruncate:     onTruncate,
+		onDrop:         onDrop,
+		tableInfo:      tableInfo,
+	}, nil
+}
+
+func applyColumnMapping(attr *attribute, m config.MappingColumn) error {
+	cType, err := castTypeFromString(m.Cast)
+	if err != nil {
+		return err
+	}
+
+	if err := attr.castTo(cType); err != nil {
+		return err
+	}
+
+	onInvalid, err := invalidPolicyFromString(m.OnInvalid)
+	if err != nil {
+		return err
+	}
+
+	if m.TimeZone != "" {
+		if !isTemporalCast(cType) {
+			return fmt.Errorf("time_zone of column %s requires a temporal cast", attr.name)
+		}
+
+		attr.location, err = parseTimeZone(m.TimeZone)
+		if err != nil {
+			return err
+		}
+	}
+
+	attr.onNull = m.OnNull
+	attr.strict = m.Strict || m.OnInvalid != ""
+	attr.onInvalid = onInvalid
+
+	return nil
+}
+
+// newExtractedAttrs makes the attributes of JSON paths extracted from the column,
+// they follow the attribute of the column in the tuple. The invalid JSON documents
+// are handled by on_invalid policy of the column.
+func newExtractedAttrs(attr *attribute, paths []config.MappingJSONPath) ([]*attribute, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	if !castApplicable(castJSON, attr.vType) {
+		return nil, fmt.Errorf("JSON paths can not be extracted from column %s", attr.name)
+	}
+
+	extracted := make([]*attribute, 0, len(paths))
+	for i, p := range paths {
+		if p.Field == "" {
+			return nil, fmt.Errorf("field of JSON path %q of column %s is not set", p.Path, attr.name)
+		}
+
+		path, err := parseJSONPath(p.Path)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", attr.name, err)
+		}
+
+		extracted = append(extracted, &attribute{
+			colIndex:  attr.colIndex,
+			tupIndex:  attr.tupIndex + uint64(i) + 1,
+			name:      attr.name,
+			vType:     attr.vType,
+			cType:     castJSON,
+			onNull:    p.OnNull,
+			strict:    attr.strict,
+			onInvalid: attr.onInvalid,
+			field:     p.Field,
+			path:      path,
+		})
+	}
+
+	return extracted, nil
+}
+
+// rebuild makes the rule of the altered table. The column indexes are
+// recomputed by names, the dropped columns are handled by the schema change policy.
+// Returns the names of the dropped columns.
+func (r *rule) rebuild(tableInfo *schema.Table) (*rule, []string, error) {
+	pks, droppedPKs := rebuildAttrs(tableInfo, r.pks)
+	attrs, dropped := rebuildAttrs(tableInfo, r.attrs)
+	dropped = append(droppedPKs, dropped...)
+
+	filter, droppedFiltered, err := r.filter.rebuild(tableInfo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid where of %s.%s: %w", r.schema, r.table, err)
+	}
+	for _, name := range droppedFiltered {
+		if !containsString(dropped, name) {
+			dropped = append(dropped, name)
+		}
+	}
+
+	rebuilt := *r
+	rebuilt.pks = pks
+	rebuilt.attrs = attrs
+	rebuilt.filter = filter
+	rebuilt.paused = false
+	rebuilt.tableInfo = tableInfo
+
+	if len(dropped) == 0 {
+		return &rebuilt, nil, nil
+	}
+
+	switch {
+	case r.onSchemaChange == schemaChangePause:
+		rebuilt.paused = true
+	case r.onSchemaChange == schemaChangeDropField && len(droppedPKs) == 0 && len(droppedFiltered) == 0:
+	default:
+		return nil, dropped, fmt.Errorf("mapped columns %v are dropped from table %s.%s", dropped, r.schema, r.table)
+	}
+
+	return &rebuilt, dropped, nil
+}
+
+// fetchValue fetches the attribute value of the row. In strict mode the values
+// which can not be cast exactly are handled by on_invalid policy of the attribute,
+// ok is false if the row is skipped. The errors contain the primary key of the row.
+func (r *rule) fetchValue(attr *attribute, row []interface{}) (interface{}, bool, error) {
+	value, err := attr.fetchValue(row)
+	if err == nil {
+		return value, true, nil
+	}
+
+	var invalid *conversionError
+	if !attr.strict || !errors.As(err, &invalid) {
+		return nil, false, fmt.Errorf("row %v: %w", r.rowKey(row), err)
+	}
+
+	switch attr.onInvalid {
+	case invalidClamp:
+		if invalid.clamped != nil {
+			r.reportInvalid(attr, row, invalid)
+
+			return invalid.clamped, true, nil
+		}
+	case invalidDefault:
+		if value, castErr := attr.defaultValue(); castErr == nil {
+			r.reportInvalid(attr, row, invalid)
+
+			return value, true, nil
+		}
+	case invalidLog:
+		r.reportInvalid(attr, row, invalid)
+
+		return nil, false, nil
+	}
+
+	return nil, false, fmt.Errorf("row %v: %w", r.rowKey(row), err)
+}
+
+// rowKey returns the primary key values of the row as they are in MySQL.
+func (r *rule) rowKey(row []interface{}) []interface{} {
+	key := make([]interface{}, 0, len(r.pks))
+	for _, pk := range r.pks {
+		var v interface{}
+		if !pk.dropped && pk.colIndex < uint64(len(row)) {
+			v = row[pk.colIndex]
+		}
+		key = append(key, v)
+	}
+
+	return key
+}
+
+func (r *rule) reportInvalid(attr *attribute, row []interface{}, invalid *conversionError) {
+	metrics.AddInvalidValue(ruleKey(r.schema, r.table), attr.name, attr.onInvalid.String())
+
+	if r.logger == nil {
+		return
+	}
+
+	event := r.logger.Warn()
+	if attr.onInvalid == invalidLog {
+		event = r.logger.Error()
+	}
+
+	event.
+		Str("schema", r.schema).
+		Str("table", r.table).
+		Str("space", r.space).
+		Str("column", attr.name).
+		Interface("key", r.rowKey(row)).
+		Interface("value", invalid.value).
+		Str("reason", invalid.reason).
+		Str("policy", attr.onInvalid.String()).
+		Msg("invalid value")
+}
+
+// match reports whether the row is replicated by the rule.
+func (r *rule) match(row []interface{}) bool {
+	return r.filter == nil || r.filter.match(row)
+}
+
+func rebuildAttrs(table *schema.Table, attrs []*attribute) ([]*attribute, []string) {
+	var dropped []string
+
+	rebuilt := make([]*attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		a := *attr
+
+		idx := table.FindColumn(a.name)
+		if idx == -1 {
+			a.dropped = true
+			if !containsString(dropped, a.name) {
+				dropped = append(dropped, a.name)
+			}
+		} else {
+			a.colIndex = uint64(idx)
+			a.setColumn(&table.Columns[idx])
+			a.dropped = false
+		}
+
+		rebuilt = append(rebuilt, &a)
+	}
+
+	return rebuilt, dropped
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
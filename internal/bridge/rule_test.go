@@ -1,9 +1,14 @@
 package bridge
 
 import (
+	"math"
 	"testing"
 
+	"github.com/siddontang/go-mysql/schema"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
 )
 
 func Test_ruleKey(t *testing.T) {
@@ -24,3 +29,441 @@ func Test_ruleKey(t *testing.T) {
 		assert.Equal(t, tt.want, got)
 	}
 }
+
+func newTestUsersTable() *schema.Table {
+	table := &schema.Table{
+		Schema: "city",
+		Name:   "users",
+	}
+	table.AddColumn("id", "int(11) unsigned", "", "")
+	table.AddColumn("username", "varchar(255)", "", "")
+	table.AddColumn("email", "varchar(255)", "", "")
+	table.PKColumns = []int{0}
+
+	return table
+}
+
+func newTestMapping(space string, columns, key []string) config.Mapping {
+	var m config.Mapping
+	m.Source.Schema = "city"
+	m.Source.Table = "users"
+	m.Source.Columns = columns
+	m.Dest.Space = space
+	m.Dest.Key = key
+
+	return m
+}
+
+func withColumn(m config.Mapping, name string, column config.MappingColumn) config.Mapping {
+	if m.Dest.Column == nil {
+		m.Dest.Column = make(map[string]config.MappingColumn)
+	}
+	m.Dest.Column[name] = column
+
+	return m
+}
+
+func Test_newRule(t *testing.T) {
+	table := newTestUsersTable()
+
+	tests := []struct {
+		name      string
+		mapping   config.Mapping
+		wantPKs   []string
+		wantAttrs []string
+		wantErr   bool
+	}{
+		{
+			name:      "PrimaryKey",
+			mapping:   newTestMapping("users", []string{"username", "email"}, nil),
+			wantPKs:   []string{"id"},
+			wantAttrs: []string{"username", "email"},
+		},
+		{
+			name:      "PrimaryKeyInColumns",
+			mapping:   newTestMapping("users", []string{"id", "username", "email"}, nil),
+			wantPKs:   []string{"id"},
+			wantAttrs: []string{"username", "email"},
+		},
+		{
+			// The key column listed among the columns must not leave a gap in the tuple.
+			name:      "PrimaryKeyBetweenColumns",
+			mapping:   newTestMapping("users", []string{"username", "id", "email"}, nil),
+			wantPKs:   []string{"id"},
+			wantAttrs: []string{"username", "email"},
+		},
+		{
+			name:      "CustomKey",
+			mapping:   newTestMapping("users_by_email", []string{"id", "username"}, []string{"email"}),
+			wantPKs:   []string{"email"},
+			wantAttrs: []string{"id", "username"},
+		},
+		{
+			name:    "UnknownKey",
+			mapping: newTestMapping("users", nil, []string{"phone"}),
+			wantErr: true,
+		},
+		{
+			name:    "UnknownColumn",
+			mapping: newTestMapping("users", []string{"phone"}, nil),
+			wantErr: true,
+		},
+		{
+			name: "CastKey",
+			mapping: withColumn(newTestMapping("users", []string{"username"}, nil), "id",
+				config.MappingColumn{Cast: "string"}),
+			wantPKs:   []string{"id"},
+			wantAttrs: []string{"username"},
+		},
+		{
+			name: "UnknownCast",
+			mapping: withColumn(newTestMapping("users", []string{"username"}, nil), "username",
+				config.MappingColumn{Cast: "varchar"}),
+			wantErr: true,
+		},
+		{
+			name: "InapplicableCast",
+			mapping: withColumn(newTestMapping("users", []string{"username"}, nil), "id",
+				config.MappingColumn{Cast: "unix_timestamp"}),
+			wantErr: true,
+		},
+		{
+			name: "TimeZoneWithoutTemporalCast",
+			mapping: withColumn(newTestMapping("users", []string{"username"}, nil), "username",
+				config.MappingColumn{Cast: "string", TimeZone: "+03:00"}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newRule(tt.mapping, table)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.mapping.Dest.Space, got.space)
+
+			tupIndex := uint64(0)
+			pks := make([]string, 0, len(got.pks))
+			for _, pk := range got.pks {
+				assert.Equal(t, tupIndex, pk.tupIndex)
+				tupIndex++
+				pks = append(pks, pk.name)
+			}
+			attrs := make([]string, 0, len(got.attrs))
+			for _, attr := range got.attrs {
+				assert.Equal(t, tupIndex, attr.tupIndex)
+				tupIndex++
+				attrs = append(attrs, attr.name)
+			}
+
+			assert.Equal(t, tt.wantPKs, pks)
+			assert.Equal(t, tt.wantAttrs, attrs)
+		})
+	}
+}
+
+func Test_rule_rebuild(t *testing.T) {
+	newAlteredTable := func(columns ...string) *schema.Table {
+		table := &schema.Table{
+			Schema: "city",
+			Name:   "users",
+		}
+		for _, name := range columns {
+			table.AddColumn(name, "varchar(255)", "", "")
+		}
+		table.PKColumns = []int{table.FindColumn("id")}
+
+		return table
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		where       string
+		table       *schema.Table
+		wantIndexes []uint64
+		wantDropped []string
+		wantPaused  bool
+		wantErr     bool
+	}{
+		{
+			name:        "AddColumnFirst",
+			table:       newAlteredTable("created_at", "id", "username", "email"),
+			wantIndexes: []uint64{1, 2, 3},
+		},
+		{
+			name:        "DropUnmappedColumn",
+			table:       newAlteredTable("id", "username", "email"),
+			wantIndexes: []uint64{0, 1, 2},
+		},
+		{
+			name:        "DropColumn_Fail",
+			table:       newAlteredTable("id", "email"),
+			wantDropped: []string{"username"},
+			wantErr:     true,
+		},
+		{
+			name:        "DropColumn_Pause",
+			policy:      "pause",
+			table:       newAlteredTable("id", "email"),
+			wantIndexes: []uint64{0, 0, 1},
+			wantDropped: []string{"username"},
+			wantPaused:  true,
+		},
+		{
+			name:        "DropColumn_DropField",
+			policy:      "drop_field",
+			table:       newAlteredTable("id", "email"),
+			wantIndexes: []uint64{0, 0, 1},
+			wantDropped: []string{"username"},
+		},
+		{
+			name:        "DropKeyColumn_DropField",
+			policy:      "drop_field",
+			table:       newAlteredTable("uid", "username", "email"),
+			wantDropped: []string{"id"},
+			wantErr:     true,
+		},
+		{
+			name:        "DropFilteredColumn_DropField",
+			policy:      "drop_field",
+			where:       "email IS NOT NULL",
+			table:       newAlteredTable("id", "username"),
+			wantDropped: []string{"email"},
+			wantErr:     true,
+		},
+		{
+			name:        "DropFilteredColumn_Pause",
+			policy:      "pause",
+			where:       "email IS NOT NULL",
+			table:       newAlteredTable("id", "username"),
+			wantIndexes: []uint64{0, 1, 0},
+			wantDropped: []string{"email"},
+			wantPaused:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := newTestMapping("users", []string{"username", "email"}, nil)
+			mapping.Dest.OnSchemaChange = tt.policy
+			mapping.Source.Where = tt.where
+
+			r, err := newRule(mapping, newTestUsersTable())
+			require.NoError(t, err)
+
+			got, dropped, err := r.rebuild(tt.table)
+			assert.Equal(t, tt.wantDropped, dropped)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantPaused, got.paused)
+			assert.Same(t, tt.table, got.tableInfo)
+
+			indexes := make([]uint64, 0, len(got.pks)+len(got.attrs))
+			for _, attr := range append(got.pks, got.attrs...) {
+				idx := attr.colIndex
+				if attr.dropped {
+					idx = 0
+				}
+				indexes = append(indexes, idx)
+			}
+			assert.Equal(t, tt.wantIndexes, indexes)
+
+			// The original rule is kept as is.
+			assert.Equal(t, uint64(1), r.attrs[0].colIndex)
+			assert.False(t, r.attrs[0].dropped)
+		})
+	}
+}
+
+func Test_schemaChangePolicyFromString(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    schemaChangePolicy
+		wantErr bool
+	}{
+		{str: "", want: schemaChangeFail},
+		{str: "fail", want: schemaChangeFail},
+		{str: "pause", want: schemaChangePause},
+		{str: "drop_field", want: schemaChangeDropField},
+		{str: "ignore", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := schemaChangePolicyFromString(tt.str)
+		if tt.wantErr {
+			assert.Error(t, err)
+
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+}
+
+func Test_rule_fetchValue_Invalid(t *testing.T) {
+	table := &schema.Table{
+		Schema: "city",
+		Name:   "logins",
+	}
+	table.AddColumn("id", "int(11)", "", "")
+	table.AddColumn("attempts", "int(11)", "", "")
+	table.AddColumn("rating", "double", "", "")
+	table.PKColumns = []int{0}
+
+	tests := []struct {
+		name    string
+		column  config.MappingColumn
+		row     []interface{}
+		want    interface{}
+		wantOK  bool
+		wantErr string
+	}{
+		{
+			name:   "NotStrict_Negative",
+			column: config.MappingColumn{Cast: "unsigned"},
+			row:    []interface{}{int64(1), int64(-1), 0.5},
+			want:   uint64(18446744073709551615),
+			wantOK: true,
+		},
+		{
+			name:    "Strict_Negative",
+			column:  config.MappingColumn{Cast: "unsigned", Strict: true},
+			row:     []interface{}{int64(7), int64(-1), 0.5},
+			wantErr: "row [7]: column attempts: could not cast -1 to uint64: negative",
+		},
+		{
+			name:   "Strict_Valid",
+			column: config.MappingColumn{Cast: "unsigned", Strict: true},
+			row:    []interface{}{int64(7), int64(3), 0.5},
+			want:   uint64(3),
+			wantOK: true,
+		},
+		{
+			name:   "Clamp_Negative",
+			column: config.MappingColumn{Cast: "unsigned", OnInvalid: "clamp"},
+			row:    []interface{}{int64(7), int64(-1), 0.5},
+			want:   uint64(0),
+			wantOK: true,
+		},
+		{
+			name:   "Default",
+			column: config.MappingColumn{Cast: "unsigned", OnNull: 100, OnInvalid: "default"},
+			row:    []interface{}{int64(7), int64(-1), 0.5},
+			want:   uint64(100),
+			wantOK: true,
+		},
+		{
+			name:   "Default_NoOnNull",
+			column: config.MappingColumn{Cast: "unsigned", OnInvalid: "default"},
+			row:    []interface{}{int64(7), int64(-1), 0.5},
+			want:   nil,
+			wantOK: true,
+		},
+		{
+			name:   "Log",
+			column: config.MappingColumn{Cast: "unsigned", OnInvalid: "log"},
+			row:    []interface{}{int64(7), int64(-1), 0.5},
+			wantOK: false,
+		},
+		{
+			name:    "Fail",
+			column:  config.MappingColumn{Cast: "unsigned", OnInvalid: "fail"},
+			row:     []interface{}{int64(7), int64(-1), 0.5},
+			wantErr: "row [7]: column attempts: could not cast -1 to uint64: negative",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := newTestMapping("logins", []string{"attempts"}, nil)
+			mapping.Dest.Column = map[string]config.MappingColumn{"attempts": tt.column}
+
+			r, err := newRule(mapping, table)
+			require.NoError(t, err)
+
+			got, ok, err := r.fetchValue(r.attrs[0], tt.row)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_rule_fetchValue_Clamp(t *testing.T) {
+	table := &schema.Table{
+		Schema: "city",
+		Name:   "logins",
+	}
+	table.AddColumn("id", "int(11)", "", "")
+	table.AddColumn("rating", "double", "", "")
+	table.PKColumns = []int{0}
+
+	tests := []struct {
+		cast  string
+		value interface{}
+		want  interface{}
+	}{
+		{cast: "unsigned", value: 2.7, want: uint64(2)},
+		{cast: "unsigned", value: -2.7, want: uint64(0)},
+		{cast: "unsigned", value: 1e30, want: uint64(math.MaxUint64)},
+		{cast: "integer", value: -2.7, want: int64(-2)},
+		{cast: "integer", value: 1e30, want: int64(math.MaxInt64)},
+		{cast: "integer", value: -1e30, want: int64(math.MinInt64)},
+	}
+
+	for _, tt := range tests {
+		mapping := newTestMapping("logins", []string{"rating"}, nil)
+		mapping.Dest.Column = map[string]config.MappingColumn{
+			"rating": {Cast: tt.cast, OnInvalid: "clamp"},
+		}
+
+		r, err := newRule(mapping, table)
+		require.NoError(t, err)
+
+		got, ok, err := r.fetchValue(r.attrs[0], []interface{}{int64(1), tt.value})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, tt.want, got, "%s %v", tt.cast, tt.value)
+	}
+
+	// The value which can not be clamped fails.
+	mapping := newTestMapping("logins", []string{"rating"}, nil)
+	mapping.Dest.Column = map[string]config.MappingColumn{
+		"rating": {Cast: "integer", OnInvalid: "clamp"},
+	}
+	r, err := newRule(mapping, table)
+	require.NoError(t, err)
+
+	_, _, err = r.fetchValue(r.attrs[0], []interface{}{int64(1), "many"})
+	assert.Error(t, err)
+}
+
+func Test_invalidPolicyFromString(t *testing.T) {
+	for _, p := range []invalidPolicy{invalidFail, invalidClamp, invalidDefault, invalidLog} {
+		got, err := invalidPolicyFromString(p.String())
+		assert.NoError(t, err)
+		assert.Equal(t, p, got)
+	}
+
+	_, err := invalidPolicyFromString("ignore")
+	assert.Error(t, err)
+}
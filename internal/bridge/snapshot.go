@@ -0,0 +1,466 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-mysql/client"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/schema"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/metrics"
+)
+
+// snapshotConfig contains options to copy tables by chunks,
+// it is used by the native initial snapshot and by resync.
+type snapshotConfig struct {
+	addr        string
+	user        string
+	password    string
+	gtidMode    bool
+	chunkSize   int
+	parallelism int
+	native      bool // the initial snapshot is made without mysqldump
+}
+
+// snapshot copies the mapped tables by primary key chunks using
+// consistent reads, so it does not require mysqldump.
+// The interrupted snapshot continues from the saved progress.
+//
+// Returns the position the snapshot is consistent with,
+// replication must continue from this position.
+func (b *Bridge) snapshot() (position, error) {
+	workers := b.snapshotCfg.parallelism
+	if workers < 1 {
+		workers = 1
+	}
+
+	saved, err := b.stateSaver.loadDump()
+	if err != nil {
+		return nil, err
+	}
+
+	var conns []*client.Conn
+	defer func() {
+		closeConns(conns)
+	}()
+
+	if saved != nil {
+		// The snapshot transactions are lost, so the rest of the tables
+		// is read as is, the binlog replay from the saved position fixes the difference
+		// applying the inserts as replace until the position the reads are finished at.
+		conns, err = b.connectSnapshot(workers, false)
+		if err != nil {
+			return nil, err
+		}
+
+		b.dump.restore(saved)
+
+		b.logger.Info().
+			Str("pos", saved.position().String()).
+			Int("workers", workers).
+			Msg("resume snapshot")
+	} else {
+		var pos position
+		conns, pos, err = b.startSnapshot(workers)
+		if err != nil {
+			return nil, err
+		}
+
+		b.dump.restore(newDumpState(pos))
+		if err = b.stateSaver.saveDump(b.dump); err != nil {
+			return nil, err
+		}
+
+		b.logger.Info().
+			Str("pos", pos.String()).
+			Int("workers", workers).
+			Msg("start snapshot")
+	}
+
+	all := b.tables()
+	tables := make(chan []*rule, len(all))
+	for _, rules := range all {
+		key := ruleKey(rules[0].schema, rules[0].table)
+		progress := b.dump.table(key)
+		metrics.SetDumpProgress(key, progress.Rows, progress.Done)
+		if !progress.Done {
+			tables <- rules
+		}
+	}
+	close(tables)
+
+	errCh := make(chan error, len(conns))
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *client.Conn) {
+			defer wg.Done()
+
+			for rules := range tables {
+				if err := b.snapshotTable(conn, rules); err != nil {
+					errCh <- fmt.Errorf("snapshot %s.%s: %w", rules[0].schema, rules[0].table, err)
+
+					return
+				}
+			}
+
+			if _, err := conn.Execute("COMMIT"); err != nil {
+				errCh <- err
+			}
+		}(conn)
+	}
+	wg.Wait()
+	close(errCh)
+
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+
+	if saved != nil {
+		until, err := b.masterPosition()
+		if err != nil {
+			return nil, err
+		}
+
+		b.syncCh <- &dumpResumed{until: until}
+	}
+
+	pos := b.dump.position()
+
+	b.logger.Info().
+		Str("pos", pos.String()).
+		Msg("snapshot done")
+
+	return pos, nil
+}
+
+// startSnapshot opens connections with consistent snapshot transactions
+// and returns the position the transactions are consistent with.
+func (b *Bridge) startSnapshot(workers int) ([]*client.Conn, position, error) {
+	lockConn, err := b.connectSource()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = lockConn.Close()
+	}()
+
+	// Writes are locked until every worker starts its transaction
+	// and the position is read, so they all see the same data.
+	if _, err = lockConn.Execute("FLUSH TABLES WITH READ LOCK"); err != nil {
+		return nil, nil, fmt.Errorf("could not lock tables: %w", err)
+	}
+
+	conns, err := b.connectSnapshot(workers, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pos, err := b.masterPosition()
+	if err != nil {
+		closeConns(conns)
+
+		return nil, nil, err
+	}
+
+	if _, err = lockConn.Execute("UNLOCK TABLES"); err != nil {
+		closeConns(conns)
+
+		return nil, nil, err
+	}
+
+	return conns, pos, nil
+}
+
+func (b *Bridge) connectSource() (*client.Conn, error) {
+	cfg := b.snapshotCfg
+
+	conn, err := client.Connect(cfg.addr, cfg.user, cfg.password, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// TIMESTAMP values are read in UTC as they are dumped by mysqldump.
+	if _, err := conn.Execute("SET time_zone = '+00:00'"); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (b *Bridge) connectSnapshot(workers int, consistent bool) ([]*client.Conn, error) {
+	conns := make([]*client.Conn, 0, workers)
+	for i := 0; i < workers; i++ {
+		conn, err := b.connectSource()
+		if err != nil {
+			closeConns(conns)
+
+			return nil, err
+		}
+		conns = append(conns, conn)
+
+		if !consistent {
+			continue
+		}
+
+		if _, err = conn.Execute("SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+			closeConns(conns)
+
+			return nil, err
+		}
+		if _, err = conn.Execute("START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+			closeConns(conns)
+
+			return nil, err
+		}
+	}
+
+	return conns, nil
+}
+
+func closeConns(conns []*client.Conn) {
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
+
+func (b *Bridge) masterPosition() (position, error) {
+	if b.snapshotCfg.gtidMode {
+		set, err := b.canal.GetMasterGTIDSet()
+		if err != nil {
+			return nil, err
+		}
+
+		return newGTIDSet(set), nil
+	}
+
+	pos, err := b.canal.GetMasterPos()
+	if err != nil {
+		return nil, err
+	}
+
+	return newBinlogPos(pos), nil
+}
+
+// snapshotTable copies the table starting after the saved primary key,
+// the rows are followed by the progress.
+func (b *Bridge) snapshotTable(conn *client.Conn, rules []*rule) error {
+	key := ruleKey(rules[0].schema, rules[0].table)
+
+	return b.copyTable(conn, rules, b.dump.table(key).LastPK, func(last []interface{}, rows int, done bool) {
+		b.syncCh <- &dumpChunk{
+			table:  key,
+			lastPK: last,
+			rows:   rows,
+			done:   done,
+		}
+	})
+}
+
+// copyTable reads the table by chunks ordered by primary key starting
+// after the given primary key and sends the rows to sync as dump rows
+// of all the rules of the table. onChunk is called after each chunk is sent.
+func (b *Bridge) copyTable(conn *client.Conn, rules []*rule, last []interface{}, onChunk func(last []interface{}, rows int, done bool)) error {
+	table := rules[0].tableInfo
+	chunkSize := b.snapshotCfg.chunkSize
+
+	for {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+
+		query := buildChunkQuery(table, chunkSize, last != nil)
+		res, err := conn.Execute(query, last...)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]interface{}, 0, len(res.Values))
+		for _, values := range res.Values {
+			row, err := snapshotRow(table, values)
+			if err != nil {
+				return err
+			}
+
+			rows = append(rows, row)
+		}
+
+		done := len(rows) < chunkSize
+		if len(rows) == 0 {
+			onChunk(nil, 0, done)
+
+			return nil
+		}
+
+		var reqs []*request
+		for _, r := range rules {
+			ruleReqs, err := makeInsertBatch(r, rows)
+			if err != nil {
+				return err
+			}
+
+			reqs = append(reqs, ruleReqs...)
+		}
+
+		// The chunk may be copied again after restart.
+		for _, req := range reqs {
+			req.onConflict = conflictReplace
+		}
+
+		b.syncCh <- &batch{
+			action: actionInsert,
+			reqs:   reqs,
+			dump:   true,
+		}
+
+		last = chunkKey(table, rows[len(rows)-1])
+
+		onChunk(last, len(rows), done)
+
+		if done {
+			return nil
+		}
+	}
+}
+
+// replaceInserts applies the inserts as replace.
+func replaceInserts(batches []*batch) {
+	for _, bt := range batches {
+		for _, req := range bt.reqs {
+			if req.action == actionInsert {
+				req.onConflict = conflictReplace
+			}
+		}
+	}
+}
+
+// chunkKey returns the primary key of the row passed to the next chunk query
+// and saved in the dump state, DECIMAL values are passed as exact strings.
+func chunkKey(table *schema.Table, row []interface{}) []interface{} {
+	key := make([]interface{}, 0, len(table.PKColumns))
+	for _, idx := range table.PKColumns {
+		v := row[idx]
+		if d, ok := v.(decimal.Decimal); ok {
+			v = d.String()
+		}
+
+		key = append(key, v)
+	}
+
+	return key
+}
+
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// buildChunkQuery returns the query to select the next chunk of rows
+// ordered by primary key. If afterPK is true, the query expects
+// the values of the last read primary key as arguments.
+func buildChunkQuery(table *schema.Table, chunkSize int, afterPK bool) string {
+	cols := make([]string, 0, len(table.Columns))
+	for _, col := range table.Columns {
+		cols = append(cols, quoteName(col.Name))
+	}
+
+	pks := make([]string, 0, len(table.PKColumns))
+	placeholders := make([]string, 0, len(table.PKColumns))
+	for _, idx := range table.PKColumns {
+		pks = append(pks, quoteName(table.Columns[idx].Name))
+		placeholders = append(placeholders, "?")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(strings.Join(cols, ", "))
+	sb.WriteString(" FROM ")
+	sb.WriteString(quoteName(table.Schema))
+	sb.WriteByte('.')
+	sb.WriteString(quoteName(table.Name))
+	if afterPK {
+		sb.WriteString(" WHERE (")
+		sb.WriteString(strings.Join(pks, ", "))
+		sb.WriteString(") > (")
+		sb.WriteString(strings.Join(placeholders, ", "))
+		sb.WriteByte(')')
+	}
+	sb.WriteString(" ORDER BY ")
+	sb.WriteString(strings.Join(pks, ", "))
+	sb.WriteString(" LIMIT ")
+	sb.WriteString(strconv.Itoa(chunkSize))
+
+	return sb.String()
+}
+
+// snapshotRow converts the selected values to the same types
+// canal produces parsing mysqldump output.
+func snapshotRow(table *schema.Table, values []mysql.FieldValue) ([]interface{}, error) {
+	if len(values) != len(table.Columns) {
+		return nil, fmt.Errorf("invalid row length %d, table %s has %d columns", len(values), table, len(table.Columns))
+	}
+
+	row := make([]interface{}, len(values))
+	for i := range values {
+		v, err := snapshotValue(&table.Columns[i], values[i].Value())
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", table.Columns[i].Name, err)
+		}
+
+		row[i] = v
+	}
+
+	return row, nil
+}
+
+// snapshotValue converts the value returned by the client
+// (nil, uint64, int64, float64 or []byte).
+func snapshotValue(col *schema.TableColumn, value interface{}) (interface{}, error) {
+	isInt := col.Type == schema.TYPE_NUMBER || col.Type == schema.TYPE_MEDIUM_INT
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case uint64:
+		if !isInt {
+			return strconv.FormatUint(v, 10), nil
+		}
+		if col.IsUnsigned {
+			return v, nil
+		}
+
+		return int64(v), nil
+	case int64:
+		if !isInt {
+			return strconv.FormatInt(v, 10), nil
+		}
+		if col.IsUnsigned {
+			return uint64(v), nil
+		}
+
+		return v, nil
+	case float64:
+		return v, nil
+	case []byte:
+		s := string(v)
+		switch {
+		case isInt && col.IsUnsigned:
+			return strconv.ParseUint(s, 10, 64)
+		case isInt:
+			return strconv.ParseInt(s, 10, 64)
+		case col.Type == schema.TYPE_FLOAT:
+			return strconv.ParseFloat(s, 64)
+		case col.Type == schema.TYPE_DECIMAL:
+			return decimal.NewFromString(s)
+		}
+
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("unexpected value type %T", value)
+}
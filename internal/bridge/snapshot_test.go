@@ -0,0 +1,151 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-mysql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLoginsTable() *schema.Table {
+	table := &schema.Table{
+		Schema: "city",
+		Name:   "logins",
+	}
+	table.AddColumn("username", "varchar(16)", "", "")
+	table.AddColumn("ip", "varchar(16)", "", "")
+	table.AddColumn("date", "int unsigned", "", "")
+	table.AddColumn("attempts", "bigint(20)", "", "")
+	table.PKColumns = []int{0, 1, 2}
+
+	return table
+}
+
+func Test_buildChunkQuery(t *testing.T) {
+	table := newTestLoginsTable()
+
+	got := buildChunkQuery(table, 100, false)
+	assert.Equal(t, "SELECT `username`, `ip`, `date`, `attempts` FROM `city`.`logins` ORDER BY `username`, `ip`, `date` LIMIT 100", got)
+
+	got = buildChunkQuery(table, 100, true)
+	assert.Equal(t, "SELECT `username`, `ip`, `date`, `attempts` FROM `city`.`logins` WHERE (`username`, `ip`, `date`) > (?, ?, ?) ORDER BY `username`, `ip`, `date` LIMIT 100", got)
+}
+
+func Test_quoteName(t *testing.T) {
+	assert.Equal(t, "`users`", quoteName("users"))
+	assert.Equal(t, "`us``ers`", quoteName("us`ers"))
+}
+
+func Test_snapshotValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		col     schema.TableColumn
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "Null",
+			col:   schema.TableColumn{Type: schema.TYPE_STRING},
+			value: nil,
+			want:  nil,
+		},
+		{
+			name:  "String",
+			col:   schema.TableColumn{Type: schema.TYPE_STRING},
+			value: []byte("bob"),
+			want:  "bob",
+		},
+		{
+			name:  "SignedNumber",
+			col:   schema.TableColumn{Type: schema.TYPE_NUMBER},
+			value: int64(-10),
+			want:  int64(-10),
+		},
+		{
+			name:  "UnsignedNumber",
+			col:   schema.TableColumn{Type: schema.TYPE_NUMBER, IsUnsigned: true},
+			value: uint64(10),
+			want:  uint64(10),
+		},
+		{
+			name:  "UnsignedNumber_FromSigned",
+			col:   schema.TableColumn{Type: schema.TYPE_MEDIUM_INT, IsUnsigned: true},
+			value: int64(10),
+			want:  uint64(10),
+		},
+		{
+			name:  "Float",
+			col:   schema.TableColumn{Type: schema.TYPE_FLOAT},
+			value: 1.5,
+			want:  1.5,
+		},
+		{
+			name:  "Decimal",
+			col:   schema.TableColumn{Type: schema.TYPE_DECIMAL},
+			value: []byte("10.25"),
+			want:  decimal.New(1025, -2),
+		},
+		{
+			name:  "NumberAsString",
+			col:   schema.TableColumn{Type: schema.TYPE_NUMBER},
+			value: []byte("-42"),
+			want:  int64(-42),
+		},
+		{
+			name:    "InvalidNumber",
+			col:     schema.TableColumn{Type: schema.TYPE_NUMBER},
+			value:   []byte("abc"),
+			wantErr: true,
+		},
+		{
+			name:    "UnexpectedType",
+			col:     schema.TableColumn{Type: schema.TYPE_STRING},
+			value:   true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := snapshotValue(&tt.col, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_replaceInserts(t *testing.T) {
+	batches := []*batch{
+		{
+			action: actionInsert,
+			reqs: []*request{
+				{action: actionInsert, space: "users", onConflict: conflictError},
+				{action: actionInsert, space: "logins", onConflict: conflictSkip},
+			},
+		},
+		{
+			action: actionUpdate,
+			reqs: []*request{
+				{action: actionDelete, space: "users"},
+				{action: actionUpdate, space: "users"},
+			},
+		},
+	}
+
+	replaceInserts(batches)
+
+	assert.Equal(t, conflictReplace, batches[0].reqs[0].onConflict)
+	assert.Equal(t, conflictReplace, batches[0].reqs[1].onConflict)
+	assert.Equal(t, conflictError, batches[1].reqs[0].onConflict)
+	assert.Equal(t, conflictError, batches[1].reqs[1].onConflict)
+}
@@ -0,0 +1,214 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	tnt "github.com/viciious/go-tarantool"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+// spaceDefinition is the format and the primary index of the space
+// following the mapped table.
+type spaceDefinition struct {
+	name   string
+	format []spaceField
+	pk     []string // names of the primary index fields
+}
+
+func newSpaceDefinition(r *rule, nullable map[string]bool) *spaceDefinition {
+	def := &spaceDefinition{
+		name:   r.space,
+		format: make([]spaceField, 0, len(r.pks)+len(r.attrs)),
+		pk:     make([]string, 0, len(r.pks)),
+	}
+
+	for _, pk := range r.pks {
+		def.format = append(def.format, spaceField{
+			name:      pk.name,
+			fieldType: fieldType(pk),
+		})
+		def.pk = append(def.pk, pk.name)
+	}
+
+	for _, attr := range r.attrs {
+		def.format = append(def.format, spaceField{
+			name:      attr.fieldName(),
+			fieldType: fieldType(attr),
+			nullable:  isNullable(attr, nullable),
+		})
+	}
+
+	return def
+}
+
+// isNullable reports whether the attribute values may be null,
+// the extracted JSON paths may be missing.
+func isNullable(attr *attribute, nullable map[string]bool) bool {
+	return (nullable[attr.name] || attr.path != nil) && attr.onNull == nil
+}
+
+// lua returns Lua code creating the space, it does nothing if the space exists.
+func (d *spaceDefinition) lua() string {
+	var sb strings.Builder
+
+	sb.WriteString("do\n")
+	fmt.Fprintf(&sb, "    local space = box.schema.space.create(%s, {\n", luaString(d.name))
+	sb.WriteString("        if_not_exists = true,\n")
+	sb.WriteString("    })\n\n")
+
+	sb.WriteString("    space:format({\n")
+	for _, f := range d.format {
+		fmt.Fprintf(&sb, "        { name = %s, type = %s", luaString(f.name), luaString(f.fieldType))
+		if f.nullable {
+			sb.WriteString(", is_nullable = true")
+		}
+		sb.WriteString(" },\n")
+	}
+	sb.WriteString("    })\n\n")
+
+	parts := make([]string, 0, len(d.pk))
+	for _, name := range d.pk {
+		parts = append(parts, luaString(name))
+	}
+
+	sb.WriteString("    space:create_index('primary', {\n")
+	sb.WriteString("        type = 'tree',\n")
+	sb.WriteString("        if_not_exists = true,\n")
+	fmt.Fprintf(&sb, "        parts = { %s },\n", strings.Join(parts, ", "))
+	sb.WriteString("    })\n")
+	sb.WriteString("end\n")
+
+	return sb.String()
+}
+
+func luaString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return "'" + s + "'"
+}
+
+// fieldType returns Tarantool field type storing the values of the attribute.
+func fieldType(attr *attribute) string {
+	if attr.cType != castNone {
+		return castFieldTypes[attr.cType][0]
+	}
+
+	switch attr.vType {
+	case typeNumber, typeMediumInt, typeEnum, typeSet, typeBit:
+		if attr.unsigned {
+			return "unsigned"
+		}
+
+		return "integer"
+	case typeFloat, typeDecimal:
+		return "number"
+	case typeString, typeDatetime, typeTimestamp, typeDate, typeTime, typeBinary:
+		return "string"
+	default:
+		return "any"
+	}
+}
+
+// spaceDefinitions returns the definitions of the mapped spaces sorted by names.
+// The space shared by several tables follows the first of them.
+func (b *Bridge) spaceDefinitions() ([]*spaceDefinition, error) {
+	tables := b.tables()
+	sort.Slice(tables, func(i, j int) bool {
+		return ruleKey(tables[i][0].schema, tables[i][0].table) < ruleKey(tables[j][0].schema, tables[j][0].table)
+	})
+
+	seen := make(map[string]struct{})
+	var defs []*spaceDefinition
+	for _, rules := range tables {
+		nullable, err := b.nullableColumns(rules[0].schema, rules[0].table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range rules {
+			if _, ok := seen[r.space]; ok {
+				continue
+			}
+			seen[r.space] = struct{}{}
+
+			defs = append(defs, newSpaceDefinition(r, nullable))
+		}
+	}
+
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].name < defs[j].name
+	})
+
+	return defs, nil
+}
+
+// createSpaces creates the missing spaces of the mappings.
+func (b *Bridge) createSpaces() error {
+	defs, err := b.spaceDefinitions()
+	if err != nil {
+		return err
+	}
+
+	for _, def := range defs {
+		sch, err := b.fetchSpaceSchema(def.name)
+		if err != nil {
+			return err
+		}
+		if sch != nil {
+			continue
+		}
+
+		_, err = b.tntClient.Exec(context.Background(), &tnt.Eval{
+			Expression: def.lua(),
+		})
+		if err != nil {
+			return fmt.Errorf("could not create space %s: %w", def.name, err)
+		}
+
+		b.logger.Info().
+			Str("space", def.name).
+			Msg("space created")
+	}
+
+	return nil
+}
+
+// SpacesSchema returns Lua code creating the spaces of the mappings
+// with the format and the primary index following MySQL tables.
+func SpacesSchema(cfg *config.Config, logger zerolog.Logger) (string, error) {
+	b, err := newBridge(cfg, logger)
+	if err != nil {
+		return "", err
+	}
+
+	if err := b.newCanal(cfg); err != nil {
+		return "", err
+	}
+	defer b.canal.Close()
+
+	if err := b.newRules(cfg); err != nil {
+		return "", err
+	}
+
+	defs, err := b.spaceDefinitions()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for i, def := range defs {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(def.lua())
+	}
+
+	return sb.String(), nil
+}
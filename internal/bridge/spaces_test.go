@@ -0,0 +1,97 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_spaceDefinition_lua(t *testing.T) {
+	mapping := newTestMapping("users", []string{"username", "email"}, nil)
+	r, err := newRule(mapping, newTestUsersTable())
+	require.NoError(t, err)
+
+	def := newSpaceDefinition(r, map[string]bool{"email": true})
+
+	want := `do
+    local space = box.schema.space.create('users', {
+        if_not_exists = true,
+    })
+
+    space:format({
+        { name = 'id', type = 'unsigned' },
+        { name = 'username', type = 'string' },
+        { name = 'email', type = 'string', is_nullable = true },
+    })
+
+    space:create_index('primary', {
+        type = 'tree',
+        if_not_exists = true,
+        parts = { 'id' },
+    })
+end
+`
+	assert.Equal(t, want, def.lua())
+
+	// The generated space passes the validation.
+	sch := &spaceSchema{
+		format: def.format,
+		pk: []spacePart{
+			{field: 0, fieldType: def.format[0].fieldType},
+		},
+	}
+	assert.Empty(t, validateRule(r, sch, map[string]bool{"email": true}))
+}
+
+func Test_spaceDefinition_CustomKey(t *testing.T) {
+	mapping := newTestMapping("users_by_email", []string{"id", "username"}, []string{"email"})
+	r, err := newRule(mapping, newTestUsersTable())
+	require.NoError(t, err)
+
+	r.attrs[0].onNull = 0
+	def := newSpaceDefinition(r, map[string]bool{"id": true, "email": true})
+
+	assert.Equal(t, []string{"email"}, def.pk)
+	assert.Equal(t, []spaceField{
+		{name: "email", fieldType: "string"},
+		{name: "id", fieldType: "unsigned"},
+		{name: "username", fieldType: "string"},
+	}, def.format)
+}
+
+func Test_fieldType(t *testing.T) {
+	types := []attrType{
+		typeNumber, typeFloat, typeEnum, typeSet, typeString, typeDatetime, typeTimestamp,
+		typeDate, typeTime, typeBit, typeJSON, typeDecimal, typeMediumInt, typeBinary, typePoint,
+	}
+
+	for _, vType := range types {
+		for _, attr := range []*attribute{
+			{vType: vType},
+			{vType: vType, unsigned: true},
+			{vType: vType, cType: castUnsigned},
+			{vType: vType, cType: castInteger},
+			{vType: vType, cType: castNumber},
+			{vType: vType, cType: castDouble},
+			{vType: vType, cType: castBoolean},
+			{vType: vType, cType: castString},
+			{vType: vType, cType: castUnixTimestamp},
+		} {
+			assert.True(t, fieldTypeCompatible(attr, fieldType(attr)), "%+v", attr)
+		}
+	}
+
+	assert.Equal(t, "integer", fieldType(&attribute{vType: typeNumber}))
+	assert.Equal(t, "unsigned", fieldType(&attribute{vType: typeNumber, cType: castUnsigned}))
+	assert.Equal(t, "number", fieldType(&attribute{vType: typeDecimal}))
+	assert.Equal(t, "boolean", fieldType(&attribute{vType: typeNumber, cType: castBoolean}))
+	assert.Equal(t, "double", fieldType(&attribute{vType: typeDecimal, cType: castDouble}))
+	assert.Equal(t, "integer", fieldType(&attribute{vType: typeDatetime, cType: castUnixTimestamp}))
+	assert.False(t, fieldTypeCompatible(&attribute{vType: typeNumber, cType: castString}, "integer"))
+}
+
+func Test_luaString(t *testing.T) {
+	assert.Equal(t, `'users'`, luaString("users"))
+	assert.Equal(t, `'it\'s \\ a\nname'`, luaString("it's \\ a\nname"))
+}
@@ -0,0 +1,61 @@
+package bridge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// wkbPoint is the geometry type of POINT in WKB.
+const wkbPoint = 1
+
+// parsePoint returns the coordinates of POINT value: 4 bytes of SRID
+// followed by WKB, as MySQL stores it. X is the longitude, Y is the latitude.
+func parsePoint(i interface{}) (float64, float64, error) {
+	var b []byte
+	switch v := i.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return 0, 0, fmt.Errorf("could not cast %T to point: %v", i, i)
+	}
+
+	// SRID, byte order, geometry type, X and Y.
+	if len(b) != 4+1+4+8+8 {
+		return 0, 0, &conversionError{value: fmt.Sprintf("%x", b), target: "point", reason: "invalid length"}
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if b[4] == 0 {
+		order = binary.BigEndian
+	}
+	if order.Uint32(b[5:]) != wkbPoint {
+		return 0, 0, &conversionError{value: fmt.Sprintf("%x", b), target: "point", reason: "not a point"}
+	}
+
+	x := math.Float64frombits(order.Uint64(b[9:]))
+	y := math.Float64frombits(order.Uint64(b[17:]))
+
+	return x, y, nil
+}
+
+// castPoint represents POINT value by the array [lon, lat],
+// the map {lon = ..., lat = ...} or WKT string.
+func (a *attribute) castPoint(value interface{}) (interface{}, error) {
+	x, y, err := parsePoint(value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.cType {
+	case castArray:
+		return []interface{}{x, y}, nil
+	case castMap:
+		return map[string]interface{}{"lon": x, "lat": y}, nil
+	default:
+		return "POINT(" + strconv.FormatFloat(x, 'f', -1, 64) + " " + strconv.FormatFloat(y, 'f', -1, 64) + ")", nil
+	}
+}
@@ -0,0 +1,63 @@
+package bridge
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPoint returns POINT value as MySQL stores it.
+func newTestPoint(order binary.ByteOrder, x, y float64) []byte {
+	b := make([]byte, 25)
+	binary.LittleEndian.PutUint32(b, 4326)
+	if order == binary.LittleEndian {
+		b[4] = 1
+	}
+	order.PutUint32(b[5:], wkbPoint)
+	order.PutUint64(b[9:], math.Float64bits(x))
+	order.PutUint64(b[17:], math.Float64bits(y))
+
+	return b
+}
+
+func Test_attribute_fetchValue_Point(t *testing.T) {
+	point := newTestPoint(binary.LittleEndian, 37.6173, 55.7558)
+
+	line := newTestPoint(binary.LittleEndian, 0, 0)
+	line[5] = 2
+
+	tests := []struct {
+		name    string
+		cType   castType
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "Array", cType: castArray, value: point, want: []interface{}{37.6173, 55.7558}},
+		{name: "Map", cType: castMap, value: string(point), want: map[string]interface{}{"lon": 37.6173, "lat": 55.7558}},
+		{name: "BigEndian", cType: castArray, value: newTestPoint(binary.BigEndian, -0.5, 1), want: []interface{}{-0.5, 1.0}},
+		{name: "String", cType: castString, value: point, want: "POINT(37.6173 55.7558)"},
+		{name: "NoCast", value: point, want: point},
+		{name: "NotPoint", cType: castArray, value: line, wantErr: true},
+		{name: "Short", cType: castMap, value: point[:20], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{vType: typePoint, cType: tt.cType}
+
+			got, err := a.fetchValue([]interface{}{tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
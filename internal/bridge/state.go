@@ -1,8 +1,10 @@
 package bridge
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
@@ -10,6 +12,9 @@ import (
 
 	"github.com/siddontang/go-mysql/mysql"
 	"github.com/siddontang/go/ioutil2"
+	tnt "github.com/viciious/go-tarantool"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/tarantool"
 )
 
 const (
@@ -20,6 +25,8 @@ type position interface {
 	fmt.Stringer
 
 	equal(another position) bool
+	// contains returns true if the position includes another one.
+	contains(another position) bool
 	clone() position
 }
 
@@ -29,18 +36,29 @@ type savePos struct {
 }
 
 type gtidSet struct {
-	pos mysql.GTIDSet
+	pos    mysql.GTIDSet
+	flavor string
 }
 
 func newGTIDSet(pos mysql.GTIDSet) *gtidSet {
 	return &gtidSet{
-		pos: pos,
+		pos:    pos,
+		flavor: gtidFlavor(pos),
 	}
 }
 
+func gtidFlavor(pos mysql.GTIDSet) string {
+	if _, ok := pos.(*mysql.MariadbGTIDSet); ok {
+		return mysql.MariaDBFlavor
+	}
+
+	return mysql.MySQLFlavor
+}
+
 func (g *gtidSet) clone() position {
 	return &gtidSet{
-		pos: g.pos.Clone(),
+		pos:    g.pos.Clone(),
+		flavor: g.flavor,
 	}
 }
 
@@ -53,6 +71,15 @@ func (g *gtidSet) equal(another position) bool {
 	}
 }
 
+func (g *gtidSet) contains(another position) bool {
+	v, ok := another.(*gtidSet)
+	if !ok || g.pos == nil || v.pos == nil {
+		return false
+	}
+
+	return g.pos.Contain(v.pos)
+}
+
 func (g *gtidSet) String() string {
 	if g.pos == nil {
 		return ""
@@ -61,28 +88,49 @@ func (g *gtidSet) String() string {
 	return g.pos.String()
 }
 
+// MarshalJSON encodes the flavor only for MariaDB
+// to keep the format compatible with the previous versions.
 func (g *gtidSet) MarshalJSON() ([]byte, error) {
+	flavor := ""
+	if g.flavor == mysql.MariaDBFlavor {
+		flavor = g.flavor
+	}
+
 	return json.Marshal(&struct {
-		GTID string `json:"gtid"`
+		GTID   string `json:"gtid"`
+		Flavor string `json:"flavor,omitempty"`
 	}{
-		GTID: g.String(),
+		GTID:   g.String(),
+		Flavor: flavor,
 	})
 }
 
+// UnmarshalJSON decodes GTID set of the flavor stored in JSON,
+// the current flavor otherwise. MySQL is the default flavor.
 func (g *gtidSet) UnmarshalJSON(b []byte) error {
 	s := struct {
-		GTID string `json:"gtid"`
+		GTID   string `json:"gtid"`
+		Flavor string `json:"flavor"`
 	}{}
 	if err := json.Unmarshal(b, &s); err != nil {
 		return err
 	}
 
-	set, err := mysql.ParseGTIDSet(mysql.MySQLFlavor, s.GTID)
+	flavor := s.Flavor
+	if flavor == "" {
+		flavor = g.flavor
+	}
+	if flavor == "" {
+		flavor = mysql.MySQLFlavor
+	}
+
+	set, err := mysql.ParseGTIDSet(flavor, s.GTID)
 	if err != nil {
 		return err
 	}
 
 	g.pos = set
+	g.flavor = flavor
 
 	return nil
 }
@@ -115,6 +163,15 @@ func (b *binlogPos) equal(another position) bool {
 	}
 }
 
+func (b *binlogPos) contains(another position) bool {
+	switch v := another.(type) {
+	case *binlogPos:
+		return b.pos.Compare(v.pos) >= 0
+	default:
+		return false
+	}
+}
+
 func (b *binlogPos) String() string {
 	return b.pos.String()
 }
@@ -146,37 +203,81 @@ func (b *binlogPos) UnmarshalJSON(src []byte) error {
 	return nil
 }
 
+func emptyPosition(gtidMode bool, flavor string) position {
+	if gtidMode {
+		return newGTIDSet(mustCreateGTID(flavor, ""))
+	}
+
+	return &binlogPos{pos: mysql.Position{}}
+}
+
+func decodablePosition(gtidMode bool, flavor string) position {
+	if gtidMode {
+		return &gtidSet{flavor: flavor}
+	}
+
+	return &binlogPos{}
+}
+
+func isEmptyPosition(pos position) bool {
+	switch p := pos.(type) {
+	case *gtidSet:
+		return p.String() == ""
+	case *binlogPos:
+		return p.pos.Name == ""
+	default:
+		return pos == nil
+	}
+}
+
+// checkPositionFlavor returns error if the loaded position
+// does not belong to the configured server flavor.
+func checkPositionFlavor(pos position, flavor string) error {
+	if g, ok := pos.(*gtidSet); ok && g.flavor != flavor {
+		return fmt.Errorf("saved GTID set %q belongs to %s, but replicator is configured for %s", g, g.flavor, flavor)
+	}
+
+	return nil
+}
+
 type stateSaver interface {
 	load() (position, error)
 	save(pos position, force bool) error
 	position() position
 	close() error
+
+	// loadDump returns nil if there is no saved dump progress.
+	loadDump() (*dumpState, error)
+	saveDump(state *dumpState) error
+	removeDump() error
+}
+
+// txStateSaver saves the position within the same transaction
+// as the data changes preceding this position.
+type txStateSaver interface {
+	stateSaver
+	commit(queries []tnt.Query, pos position, force bool) error
 }
 
 type fileSaver struct {
 	pos      position
 	gtidMode bool
+	flavor   string
 	filepath string
 	savedAt  int64
 
 	mu *sync.RWMutex
 }
 
-func newFileSaver(path string, gtidMode bool) (*fileSaver, error) {
+func newFileSaver(path string, gtidMode bool, flavor string) (*fileSaver, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return nil, err
 	}
 
-	var pos position
-	if gtidMode {
-		pos = &gtidSet{pos: emptyGTID}
-	} else {
-		pos = &binlogPos{pos: mysql.Position{}}
-	}
-
 	return &fileSaver{
-		pos:      pos,
+		pos:      emptyPosition(gtidMode, flavor),
 		gtidMode: gtidMode,
+		flavor:   flavor,
 		filepath: path,
 		savedAt:  time.Now().Unix(),
 		mu:       &sync.RWMutex{},
@@ -197,14 +298,13 @@ func (s *fileSaver) load() (position, error) {
 		_ = f.Close()
 	}()
 
-	var pos position
-	if s.gtidMode {
-		pos = &gtidSet{}
-	} else {
-		pos = &binlogPos{}
+	pos := decodablePosition(s.gtidMode, s.flavor)
+	err = json.NewDecoder(f).Decode(&pos)
+	if err != nil {
+		return nil, err
 	}
 
-	err = json.NewDecoder(f).Decode(&pos)
+	err = checkPositionFlavor(pos, s.flavor)
 	if err != nil {
 		return nil, err
 	}
@@ -257,3 +357,300 @@ func (s *fileSaver) position() position {
 func (s *fileSaver) close() error {
 	return s.save(s.position(), true)
 }
+
+func (s *fileSaver) dumpFilepath() string {
+	return s.filepath + ".dump"
+}
+
+func (s *fileSaver) loadDump() (*dumpState, error) {
+	buf, err := ioutil.ReadFile(s.dumpFilepath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	state, err := unmarshalDumpState(buf, decodablePosition(s.gtidMode, s.flavor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dump progress, file: %s, what: %w", s.dumpFilepath(), err)
+	}
+
+	return state, nil
+}
+
+func (s *fileSaver) saveDump(state *dumpState) error {
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to save dump progress, what: %w", err)
+	}
+
+	err = ioutil2.WriteFileAtomic(s.dumpFilepath(), buf, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to save dump progress, file: %s, what: %w", s.dumpFilepath(), err)
+	}
+
+	return nil
+}
+
+func (s *fileSaver) removeDump() error {
+	err := os.Remove(s.dumpFilepath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+const (
+	createStateSpaceExpr = `
+local name = ...
+local space = box.schema.space.create(name, {
+    if_not_exists = true,
+    format = {
+        { name = 'key', type = 'string' },
+        { name = 'state', type = 'string' },
+    },
+})
+space:create_index('primary', {
+    type = 'hash',
+    if_not_exists = true,
+    parts = { 'key' },
+})
+`
+
+	loadStateExpr = `
+local name, key = ...
+local t = box.space[name]:get(key)
+if t == nil then
+    return nil
+end
+return t[2]
+`
+
+	saveStateExpr = `
+local name, key, state = ...
+box.space[name]:replace({ key, state })
+`
+
+	deleteStateExpr = `
+local name, key = ...
+box.space[name]:delete(key)
+`
+)
+
+// tarantoolSaver keeps the replication position in the dedicated
+// Tarantool space, so the position travels with the replicated data.
+type tarantoolSaver struct {
+	client   *tarantool.Client
+	space    string
+	key      string
+	pos      position
+	gtidMode bool
+	flavor   string
+	savedAt  int64
+
+	mu *sync.RWMutex
+}
+
+func newTarantoolSaver(client *tarantool.Client, space, key string, gtidMode bool, flavor string) (*tarantoolSaver, error) {
+	_, err := client.Exec(context.Background(), &tnt.Eval{
+		Expression: createStateSpaceExpr,
+		Tuple:      []interface{}{space},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state space, space: %s, what: %w", space, err)
+	}
+
+	return &tarantoolSaver{
+		client:   client,
+		space:    space,
+		key:      key,
+		pos:      emptyPosition(gtidMode, flavor),
+		gtidMode: gtidMode,
+		flavor:   flavor,
+		savedAt:  time.Now().Unix(),
+		mu:       &sync.RWMutex{},
+	}, nil
+}
+
+// loadState returns the state stored by the key, or empty string if there is no state.
+func (s *tarantoolSaver) loadState(key string) (string, error) {
+	res, err := s.client.Exec(context.Background(), &tnt.Eval{
+		Expression: loadStateExpr,
+		Tuple:      []interface{}{s.space, key},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 || res.Data[0][0] == nil {
+		return "", nil
+	}
+
+	state, ok := res.Data[0][0].(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected state type %T in space %s", res.Data[0][0], s.space)
+	}
+
+	return state, nil
+}
+
+func (s *tarantoolSaver) load() (position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, err := s.loadState(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sync position, space: %s, key: %s, what: %w", s.space, s.key, err)
+	}
+
+	if state == "" {
+		return s.pos, nil
+	}
+
+	pos := decodablePosition(s.gtidMode, s.flavor)
+	err = json.Unmarshal([]byte(state), &pos)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPositionFlavor(pos, s.flavor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.pos = pos
+
+	return pos, nil
+}
+
+func (s *tarantoolSaver) save(pos position, force bool) error {
+	if pos == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pos = pos
+
+	now := time.Now().Unix()
+	if !force && (now-s.savedAt < saveThreshold) {
+		return nil
+	}
+	s.savedAt = now
+
+	buf, err := json.Marshal(pos)
+	if err != nil {
+		return fmt.Errorf("failed to save sync position, pos: %s, what: %w", pos, err)
+	}
+
+	_, err = s.client.Exec(context.Background(), &tnt.Eval{
+		Expression: saveStateExpr,
+		Tuple:      []interface{}{s.space, s.key, string(buf)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save sync position, space: %s, pos: %s, what: %w", s.space, pos, err)
+	}
+
+	return nil
+}
+
+// commit applies queries and saves the position atomically.
+func (s *tarantoolSaver) commit(queries []tnt.Query, pos position, force bool) error {
+	if len(queries) == 0 {
+		return s.save(pos, force)
+	}
+
+	ops, err := makeTxOps(queries)
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(pos)
+	if err != nil {
+		return fmt.Errorf("failed to save sync position, pos: %s, what: %w", pos, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.client.Exec(context.Background(), &tnt.Eval{
+		Expression: applyTxExpr,
+		Tuple:      []interface{}{ops, []interface{}{s.space, s.key, string(buf)}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction, space: %s, pos: %s, what: %w", s.space, pos, err)
+	}
+
+	s.pos = pos
+	s.savedAt = time.Now().Unix()
+
+	return nil
+}
+
+func (s *tarantoolSaver) position() position {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.pos == nil {
+		return nil
+	}
+
+	return s.pos.clone()
+}
+
+func (s *tarantoolSaver) close() error {
+	return s.save(s.position(), true)
+}
+
+func (s *tarantoolSaver) dumpKey() string {
+	return s.key + ":dump"
+}
+
+func (s *tarantoolSaver) loadDump() (*dumpState, error) {
+	buf, err := s.loadState(s.dumpKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dump progress, space: %s, key: %s, what: %w", s.space, s.dumpKey(), err)
+	}
+
+	if buf == "" {
+		return nil, nil
+	}
+
+	state, err := unmarshalDumpState([]byte(buf), decodablePosition(s.gtidMode, s.flavor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dump progress, space: %s, key: %s, what: %w", s.space, s.dumpKey(), err)
+	}
+
+	return state, nil
+}
+
+func (s *tarantoolSaver) saveDump(state *dumpState) error {
+	buf, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to save dump progress, what: %w", err)
+	}
+
+	_, err = s.client.Exec(context.Background(), &tnt.Eval{
+		Expression: saveStateExpr,
+		Tuple:      []interface{}{s.space, s.dumpKey(), string(buf)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save dump progress, space: %s, key: %s, what: %w", s.space, s.dumpKey(), err)
+	}
+
+	return nil
+}
+
+func (s *tarantoolSaver) removeDump() error {
+	_, err := s.client.Exec(context.Background(), &tnt.Eval{
+		Expression: deleteStateExpr,
+		Tuple:      []interface{}{s.space, s.dumpKey()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove dump progress, space: %s, key: %s, what: %w", s.space, s.dumpKey(), err)
+	}
+
+	return nil
+}
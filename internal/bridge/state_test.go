@@ -1,14 +1,21 @@
 package bridge
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"github.com/siddontang/go-mysql/mysql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+	tnt "github.com/pparshin/go-mysql-tarantool/internal/tarantool"
 )
 
 func TestPosition_Clone(t *testing.T) {
@@ -42,6 +49,66 @@ func TestPosition_Clone(t *testing.T) {
 	}
 }
 
+func TestPosition_Contains(t *testing.T) {
+	gtid := func(s string) position {
+		return newGTIDSet(mustCreateGTID(mysql.MySQLFlavor, s))
+	}
+	binlog := func(name string, pos uint32) position {
+		return newBinlogPos(mysql.Position{Name: name, Pos: pos})
+	}
+
+	tests := []struct {
+		name    string
+		pos     position
+		another position
+		want    bool
+	}{
+		{
+			name:    "GTID",
+			pos:     gtid("07812e7f-5dad-11e6-b5b3-525400d2e382:1-100"),
+			another: gtid("07812e7f-5dad-11e6-b5b3-525400d2e382:1-99"),
+			want:    true,
+		},
+		{
+			name:    "GTID ahead",
+			pos:     gtid("07812e7f-5dad-11e6-b5b3-525400d2e382:1-100"),
+			another: gtid("07812e7f-5dad-11e6-b5b3-525400d2e382:1-101"),
+			want:    false,
+		},
+		{
+			name:    "Binlog",
+			pos:     binlog("mysql-bin.000002", 4),
+			another: binlog("mysql-bin.000001", 1000),
+			want:    true,
+		},
+		{
+			name:    "Binlog equal",
+			pos:     binlog("mysql-bin.000001", 1000),
+			another: binlog("mysql-bin.000001", 1000),
+			want:    true,
+		},
+		{
+			name:    "Binlog ahead",
+			pos:     binlog("mysql-bin.000001", 1000),
+			another: binlog("mysql-bin.000001", 1001),
+			want:    false,
+		},
+		{
+			name:    "Mixed",
+			pos:     binlog("mysql-bin.000001", 1000),
+			another: gtid("07812e7f-5dad-11e6-b5b3-525400d2e382:1-99"),
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.pos.contains(tt.another))
+		})
+	}
+}
+
 func TestPosition_Marshal(t *testing.T) {
 	gtid, err := mysql.ParseMysqlGTIDSet("07812e7f-5dad-11e6-b5b3-525400d2e382:1-939564")
 	require.NoError(t, err)
@@ -56,6 +123,11 @@ func TestPosition_Marshal(t *testing.T) {
 			pos:  newGTIDSet(gtid),
 			want: `{"gtid": "07812e7f-5dad-11e6-b5b3-525400d2e382:1-939564"}`,
 		},
+		{
+			name: "MariaDB_GTID",
+			pos:  newGTIDSet(mustCreateGTID(mysql.MariaDBFlavor, "0-1-100")),
+			want: `{"gtid": "0-1-100", "flavor": "mariadb"}`,
+		},
 		{
 			name: "Binlog",
 			pos: newBinlogPos(mysql.Position{
@@ -122,6 +194,61 @@ func TestPosition_Unmarshal(t *testing.T) {
 	}
 }
 
+func TestPosition_UnmarshalFlavor(t *testing.T) {
+	tests := []struct {
+		name       string
+		pos        string
+		flavor     string
+		want       position
+		wantFlavor string
+	}{
+		{
+			name:       "MariaDB",
+			pos:        `{"gtid": "0-1-100,1-2-200", "flavor": "mariadb"}`,
+			flavor:     mysql.MySQLFlavor,
+			want:       newGTIDSet(mustCreateGTID(mysql.MariaDBFlavor, "0-1-100,1-2-200")),
+			wantFlavor: mysql.MariaDBFlavor,
+		},
+		{
+			name:       "MariaDB_Empty",
+			pos:        `{"gtid": ""}`,
+			flavor:     mysql.MariaDBFlavor,
+			want:       newGTIDSet(mustCreateGTID(mysql.MariaDBFlavor, "")),
+			wantFlavor: mysql.MariaDBFlavor,
+		},
+		{
+			name:       "MySQL_Default",
+			pos:        `{"gtid": "07812e7f-5dad-11e6-b5b3-525400d2e382:1-939564"}`,
+			flavor:     "",
+			want:       newGTIDSet(mustCreateGTID(mysql.MySQLFlavor, "07812e7f-5dad-11e6-b5b3-525400d2e382:1-939564")),
+			wantFlavor: mysql.MySQLFlavor,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodablePosition(true, tt.flavor)
+			err := json.Unmarshal([]byte(tt.pos), &got)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got)
+
+			err = checkPositionFlavor(got, tt.wantFlavor)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_checkPositionFlavor(t *testing.T) {
+	pos := newGTIDSet(mustCreateGTID(mysql.MariaDBFlavor, "0-1-100"))
+	assert.NoError(t, checkPositionFlavor(pos, mysql.MariaDBFlavor))
+	assert.Error(t, checkPositionFlavor(pos, mysql.MySQLFlavor))
+
+	binlog := newBinlogPos(mysql.Position{Name: "mysql-bin.000001", Pos: 4})
+	assert.NoError(t, checkPositionFlavor(binlog, mysql.MariaDBFlavor))
+}
+
 func TestFileSaver_SaveLoad(t *testing.T) {
 	oldGTID, err := mysql.ParseMysqlGTIDSet("07812e7f-5dad-11e6-b5b3-525400d2e382:1-939564")
 	require.NoError(t, err)
@@ -161,7 +288,7 @@ func TestFileSaver_SaveLoad(t *testing.T) {
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			fs, err := newFileSaver(dataFile, tt.gtidMode)
+			fs, err := newFileSaver(dataFile, tt.gtidMode, mysql.MySQLFlavor)
 			if !assert.NoError(t, err) {
 				return
 			}
@@ -189,3 +316,133 @@ func TestFileSaver_SaveLoad(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestFileSaver_Dump(t *testing.T) {
+	dataDir := "/tmp/replicator-dump-test"
+	dataFile := path.Join(dataDir, "master.info")
+	defer func() {
+		err := os.RemoveAll(dataDir)
+		assert.NoError(t, err)
+	}()
+
+	fs, err := newFileSaver(dataFile, true, mysql.MySQLFlavor)
+	require.NoError(t, err)
+
+	got, err := fs.loadDump()
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	pos := newGTIDSet(mustCreateGTID(mysql.MySQLFlavor, "07812e7f-5dad-11e6-b5b3-525400d2e382:1-939564"))
+	state := newDumpState(pos)
+	state.update(&dumpChunk{
+		table:  ruleKey("city", "users"),
+		lastPK: []interface{}{int64(1000)},
+		rows:   1000,
+	})
+
+	err = fs.saveDump(state)
+	require.NoError(t, err)
+
+	got, err = fs.loadDump()
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, pos, got.position())
+	assert.Equal(t, tableDump{LastPK: []interface{}{int64(1000)}, Rows: 1000}, got.table(ruleKey("city", "users")))
+
+	err = fs.removeDump()
+	require.NoError(t, err)
+
+	got, err = fs.loadDump()
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestTarantoolSaver_SaveLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("test requires dev env - skipping it in a short mode.")
+	}
+
+	cfgPath, err := filepath.Abs("testdata/cfg.yml")
+	require.NoError(t, err)
+
+	cfg, err := config.ReadFromFile(cfgPath)
+	require.NoError(t, err)
+
+	connCfg := cfg.Replication.ConnectionDest
+	client := tnt.New(&tnt.Options{
+		Addr:           connCfg.Addr,
+		User:           connCfg.User,
+		Password:       connCfg.Password,
+		Retries:        connCfg.MaxRetries,
+		ConnectTimeout: connCfg.ConnectTimeout,
+		QueryTimeout:   connCfg.RequestTimeout,
+	})
+	defer client.Close()
+
+	gtid, err := mysql.ParseMysqlGTIDSet("07812e7f-5dad-11e6-b5b3-525400d2e382:1-939900")
+	require.NoError(t, err)
+
+	space := "replicator_state_test"
+
+	tests := []struct {
+		name     string
+		pos      position
+		gtidMode bool
+	}{
+		{
+			name:     "GTID",
+			pos:      newGTIDSet(gtid),
+			gtidMode: true,
+		},
+		{
+			name: "Binlog",
+			pos: newBinlogPos(mysql.Position{
+				Name: "mysql-bin.001650",
+				Pos:  394877900,
+			}),
+			gtidMode: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := newTarantoolSaver(client, space, tt.name, tt.gtidMode, mysql.MySQLFlavor)
+			require.NoError(t, err)
+
+			got, err := ts.load()
+			require.NoError(t, err)
+			assert.Equal(t, emptyPosition(tt.gtidMode, mysql.MySQLFlavor), got)
+
+			err = ts.save(tt.pos, true)
+			require.NoError(t, err)
+
+			another, err := newTarantoolSaver(client, space, tt.name, tt.gtidMode, mysql.MySQLFlavor)
+			require.NoError(t, err)
+
+			got, err = another.load()
+			require.NoError(t, err)
+			assert.Equal(t, tt.pos, got)
+
+			err = ts.saveDump(newDumpState(tt.pos))
+			require.NoError(t, err)
+
+			dump, err := another.loadDump()
+			require.NoError(t, err)
+			require.NotNil(t, dump)
+			assert.Equal(t, tt.pos, dump.position())
+
+			err = ts.removeDump()
+			require.NoError(t, err)
+
+			dump, err = another.loadDump()
+			require.NoError(t, err)
+			assert.Nil(t, dump)
+		})
+	}
+
+	_, err = client.Exec(context.Background(), &tarantool.Eval{
+		Expression: fmt.Sprintf("box.space.%s:drop()", space),
+	})
+	assert.NoError(t, err)
+}
@@ -4,13 +4,12 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/pingcap/parser"
 	"github.com/siddontang/go-mysql/canal"
 	"github.com/siddontang/go-mysql/mysql"
 	"github.com/siddontang/go-mysql/replication"
 )
 
-var emptyGTID = mustCreateGTID(mysql.MySQLFlavor, "")
-
 func mustCreateGTID(flavor, s string) mysql.GTIDSet {
 	set, err := mysql.ParseGTIDSet(flavor, s)
 	if err != nil {
@@ -23,16 +22,41 @@ func mustCreateGTID(flavor, s string) mysql.GTIDSet {
 type eventHandler struct {
 	bridge   *Bridge
 	gtidMode bool
+	parser   *parser.Parser
+
+	// Binlog rows are buffered until the transaction is committed.
+	txBatches []*batch
+	txRows    int
+	maxTxRows int
 }
 
-func newEventHandler(b *Bridge, gtidMode bool) *eventHandler {
+func newEventHandler(b *Bridge, gtidMode bool, maxTxRows int) *eventHandler {
 	return &eventHandler{
-		bridge:   b,
-		gtidMode: gtidMode,
+		bridge:    b,
+		gtidMode:  gtidMode,
+		parser:    parser.New(),
+		maxTxRows: maxTxRows,
+	}
+}
+
+// flushTx sends the buffered rows to sync as one transaction.
+func (h *eventHandler) flushTx(partial bool) {
+	if len(h.txBatches) == 0 {
+		return
 	}
+
+	h.bridge.syncCh <- &transaction{
+		batches: h.txBatches,
+		partial: partial,
+	}
+
+	h.txBatches = nil
+	h.txRows = 0
 }
 
 func (h *eventHandler) OnRotate(_ *replication.RotateEvent) error {
+	h.flushTx(false)
+
 	return h.bridge.ctx.Err()
 }
 
@@ -45,55 +69,114 @@ func (h *eventHandler) OnTableChanged(schema, table string) error {
 	return nil
 }
 
-func (h *eventHandler) OnDDL(_ mysql.Position, _ *replication.QueryEvent) error {
+func (h *eventHandler) OnDDL(_ mysql.Position, e *replication.QueryEvent) error {
+	h.flushTx(false)
+
+	ddls, err := parseDDL(h.parser, string(e.Query), string(e.Schema))
+	if err != nil {
+		// Canal has already parsed the query, so it is unlikely to happen.
+		h.bridge.logger.Err(err).
+			Str("query", string(e.Query)).
+			Msg("could not parse DDL query, skip it")
+
+		return h.bridge.ctx.Err()
+	}
+
+	for _, ddl := range ddls {
+		if err := h.bridge.applyDDL(ddl); err != nil {
+			h.bridge.cancel()
+
+			return err
+		}
+	}
+
 	return h.bridge.ctx.Err()
 }
 
 func (h *eventHandler) OnXID(_ mysql.Position) error {
+	h.flushTx(false)
+
 	return h.bridge.ctx.Err()
 }
 
 func (h *eventHandler) OnRow(e *canal.RowsEvent) error {
-	rule, ok := h.bridge.rules[ruleKey(e.Table.Schema, e.Table.Name)]
-	if !ok {
+	key := ruleKey(e.Table.Schema, e.Table.Name)
+	rules, err := h.bridge.tableRules(e.Table.Schema, e.Table.Name)
+	if errors.Is(err, ErrRuleNotExist) {
 		return nil
 	}
+	if err != nil {
+		h.bridge.cancel()
+
+		return fmt.Errorf("resolve mapping of %s.%s: %w", e.Table.Schema, e.Table.Name, err)
+	}
 
+	// The requests of all the spaces the table is replicated to
+	// are applied within one batch.
 	var reqs []*request
-	var err error
-	switch e.Action {
-	case canal.InsertAction:
-		reqs, err = makeInsertBatch(rule, e.Rows)
-	case canal.DeleteAction:
-		reqs, err = makeDeleteBatch(rule, e.Rows)
-	case canal.UpdateAction:
-		reqs, err = makeUpdateRequests(rule, e.Rows)
-	default:
-		err = fmt.Errorf("invalid rows action: %s", e.Action)
-	}
+	for _, rule := range rules {
+		if rule.paused {
+			continue
+		}
 
-	if err != nil {
-		h.bridge.cancel()
+		ruleReqs, err := makeRequests(rule, e.Action, e.Rows)
+		if err != nil {
+			h.bridge.cancel()
 
-		return fmt.Errorf("sync %s request, what: %w", e.Action, err)
+			return fmt.Errorf("sync %s request, space: %s, what: %w", e.Action, rule.space, err)
+		}
+
+		reqs = append(reqs, ruleReqs...)
 	}
 
 	batch := &batch{
 		action: action(e.Action),
 		reqs:   reqs,
+		dump:   e.Header == nil,
+	}
+
+	if batch.dump {
+		h.bridge.syncCh <- batch
+		h.bridge.syncCh <- &dumpChunk{
+			table: key,
+			rows:  len(e.Rows),
+		}
+
+		return h.bridge.ctx.Err()
 	}
 
-	h.bridge.syncCh <- batch
+	h.txBatches = append(h.txBatches, batch)
+	h.txRows += len(e.Rows)
+
+	if h.maxTxRows > 0 && h.txRows >= h.maxTxRows {
+		h.bridge.logger.Warn().
+			Int("rows", h.txRows).
+			Msg("transaction is too large, apply buffered rows non-atomically")
+
+		h.flushTx(true)
+	}
 
 	return h.bridge.ctx.Err()
 }
 
 func (h *eventHandler) OnGTID(_ mysql.GTIDSet) error {
+	// Changes of non-transactional tables are not followed by XID event,
+	// so we apply them before the next transaction begins.
+	h.flushTx(false)
+
 	return h.bridge.ctx.Err()
 }
 
 func (h *eventHandler) OnPosSynced(pos mysql.Position, set mysql.GTIDSet, force bool) error {
+	h.flushTx(false)
+
 	if h.gtidMode {
+		// Canal may modify the set later, so we copy it
+		// to be sure the position is not ahead of the synced data.
+		if set != nil {
+			set = set.Clone()
+		}
+
 		h.bridge.syncCh <- &savePos{
 			pos:   newGTIDSet(set),
 			force: force,
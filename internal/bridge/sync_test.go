@@ -0,0 +1,167 @@
+package bridge
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/siddontang/go-mysql/canal"
+	"github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/replication"
+	"github.com/siddontang/go-mysql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBridge(rules ...*rule) *Bridge {
+	b := &Bridge{
+		rules:   make(map[string][]*rule, len(rules)),
+		rulesMu: &sync.RWMutex{},
+		logger:  zerolog.Nop(),
+		syncCh:  make(chan interface{}, eventsBufSize),
+	}
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+
+	for _, r := range rules {
+		key := ruleKey(r.schema, r.table)
+		b.rules[key] = append(b.rules[key], r)
+	}
+
+	return b
+}
+
+func newTestRowsEvent(action string, rows ...[]interface{}) *canal.RowsEvent {
+	return &canal.RowsEvent{
+		Table: &schema.Table{
+			Schema: "city",
+			Name:   "users",
+		},
+		Action: action,
+		Rows:   rows,
+		Header: &replication.EventHeader{},
+	}
+}
+
+func newTestUsersRule() *rule {
+	return &rule{
+		schema: "city",
+		table:  "users",
+		pks: []*attribute{
+			{
+				colIndex: 0,
+				tupIndex: 0,
+				name:     "id",
+				vType:    typeNumber,
+				unsigned: true,
+			},
+		},
+		attrs: []*attribute{
+			{
+				colIndex: 1,
+				tupIndex: 1,
+				name:     "name",
+				vType:    typeString,
+			},
+		},
+		space: "users",
+	}
+}
+
+func TestEventHandler_BufferTransaction(t *testing.T) {
+	b := newTestBridge(newTestUsersRule())
+	h := newEventHandler(b, true, 0)
+
+	err := h.OnRow(newTestRowsEvent(canal.InsertAction, []interface{}{1, "bob"}))
+	require.NoError(t, err)
+
+	err = h.OnRow(newTestRowsEvent(canal.DeleteAction, []interface{}{2, "alice"}))
+	require.NoError(t, err)
+
+	assert.Empty(t, b.syncCh)
+
+	err = h.OnXID(mysql.Position{})
+	require.NoError(t, err)
+
+	require.Len(t, b.syncCh, 1)
+	got, ok := (<-b.syncCh).(*transaction)
+	require.True(t, ok)
+	assert.False(t, got.partial)
+	require.Len(t, got.batches, 2)
+	assert.Equal(t, actionInsert, got.batches[0].action)
+	assert.Equal(t, actionDelete, got.batches[1].action)
+	assert.Len(t, got.queries(), 2)
+}
+
+func TestEventHandler_TooLargeTransaction(t *testing.T) {
+	b := newTestBridge(newTestUsersRule())
+	h := newEventHandler(b, true, 2)
+
+	for i := 0; i < 3; i++ {
+		err := h.OnRow(newTestRowsEvent(canal.InsertAction, []interface{}{i, "bob"}))
+		require.NoError(t, err)
+	}
+
+	require.Len(t, b.syncCh, 1)
+	got, ok := (<-b.syncCh).(*transaction)
+	require.True(t, ok)
+	assert.True(t, got.partial)
+	assert.Len(t, got.batches, 2)
+
+	err := h.OnXID(mysql.Position{})
+	require.NoError(t, err)
+
+	require.Len(t, b.syncCh, 1)
+	got, ok = (<-b.syncCh).(*transaction)
+	require.True(t, ok)
+	assert.False(t, got.partial)
+	assert.Len(t, got.batches, 1)
+}
+
+func TestEventHandler_DumpRows(t *testing.T) {
+	b := newTestBridge(newTestUsersRule())
+	h := newEventHandler(b, true, 0)
+
+	e := newTestRowsEvent(canal.InsertAction, []interface{}{1, "bob"})
+	e.Header = nil
+
+	err := h.OnRow(e)
+	require.NoError(t, err)
+
+	require.Len(t, b.syncCh, 2)
+	got, ok := (<-b.syncCh).(*batch)
+	require.True(t, ok)
+	assert.True(t, got.dump)
+
+	chunk, ok := (<-b.syncCh).(*dumpChunk)
+	require.True(t, ok)
+	assert.Equal(t, ruleKey("city", "users"), chunk.table)
+	assert.Equal(t, 1, chunk.rows)
+}
+
+func TestEventHandler_FanOut(t *testing.T) {
+	byName := newTestUsersRule()
+	byName.space = "users_by_name"
+	byName.pks, byName.attrs = byName.attrs, byName.pks
+	byName.pks[0].tupIndex, byName.attrs[0].tupIndex = 0, 1
+
+	b := newTestBridge(newTestUsersRule(), byName)
+	h := newEventHandler(b, true, 0)
+
+	err := h.OnRow(newTestRowsEvent(canal.InsertAction, []interface{}{1, "bob"}))
+	require.NoError(t, err)
+
+	err = h.OnXID(mysql.Position{})
+	require.NoError(t, err)
+
+	require.Len(t, b.syncCh, 1)
+	got, ok := (<-b.syncCh).(*transaction)
+	require.True(t, ok)
+	require.Len(t, got.batches, 1)
+	require.Len(t, got.batches[0].reqs, 2)
+
+	assert.Equal(t, "users", got.batches[0].reqs[0].space)
+	assert.Equal(t, uint64(1), got.batches[0].reqs[0].keys[0].value)
+	assert.Equal(t, "users_by_name", got.batches[0].reqs[1].space)
+	assert.Equal(t, "bob", got.batches[0].reqs[1].keys[0].value)
+}
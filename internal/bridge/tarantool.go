@@ -1,12 +1,66 @@
 package bridge
 
-import tnt "github.com/viciious/go-tarantool"
-
-func makeInsertQuery(req *request) tnt.Query {
-	if req.action != actionInsert {
-		return nil
+import (
+	"fmt"
+
+	tnt "github.com/viciious/go-tarantool"
+)
+
+// applyTxExpr applies the list of operations and optionally saves
+// the replication position within one Tarantool transaction.
+//
+// Each operation is a tuple: {name, space, tuple or key, update operations}.
+// The position is a tuple: {space, key, state}.
+const applyTxExpr = `
+local ops, state = ...
+box.begin()
+local ok, err = pcall(function()
+    for _, op in ipairs(ops) do
+        local name, space = op[1], box.space[op[2]]
+        if space == nil then
+            box.error(box.error.NO_SUCH_SPACE, op[2])
+        end
+
+        if name == 'insert' then
+            space:insert(op[3])
+        elseif name == 'replace' then
+            space:replace(op[3])
+        elseif name == 'upsert' then
+            space:upsert(op[3], op[4])
+        elseif name == 'update' then
+            space:update(op[3], op[4])
+        elseif name == 'delete' then
+            space:delete(op[3])
+        else
+            error('unknown operation: ' .. tostring(name))
+        end
+    end
+
+    if state ~= nil then
+        box.space[state[1]]:replace({ state[2], state[3] })
+    end
+end)
+if not ok then
+    box.rollback()
+    error(err)
+end
+box.commit()
+`
+
+func makeQueries(req *batch) []tnt.Query {
+	switch req.action {
+	case actionUpdate:
+		return makeUpdateQueries(req.reqs)
+	case actionInsert:
+		return makeInsertQueries(req.reqs)
+	case actionDelete:
+		return makeDeleteQueries(req.reqs)
 	}
 
+	return nil
+}
+
+func makeTuple(req *request) []interface{} {
 	tuple := make([]interface{}, 0, len(req.keys)+len(req.args))
 	for _, key := range req.keys {
 		tuple = append(tuple, key.value)
@@ -15,6 +69,50 @@ func makeInsertQuery(req *request) tnt.Query {
 		tuple = append(tuple, arg.value)
 	}
 
+	return tuple
+}
+
+func makeAssignOps(req *request) []tnt.Operator {
+	set := make([]tnt.Operator, 0, len(req.args))
+	for _, arg := range req.args {
+		set = append(set, &tnt.OpAssign{
+			Field:    arg.field,
+			Argument: arg.value,
+		})
+	}
+
+	return set
+}
+
+func makeInsertQuery(req *request) tnt.Query {
+	if req.action != actionInsert {
+		return nil
+	}
+
+	tuple := makeTuple(req)
+
+	switch req.onConflict {
+	case conflictReplace:
+		return &tnt.Replace{
+			Space: req.space,
+			Tuple: tuple,
+		}
+	case conflictUpsert:
+		return &tnt.Upsert{
+			Space: req.space,
+			Tuple: tuple,
+			Set:   makeAssignOps(req),
+		}
+	case conflictSkip:
+		// Upsert without operations keeps the existing tuple untouched.
+		return &tnt.Upsert{
+			Space: req.space,
+			Tuple: tuple,
+			Set:   []tnt.Operator{},
+		}
+	case conflictError:
+	}
+
 	return &tnt.Insert{
 		Space: req.space,
 		Tuple: tuple,
@@ -48,28 +146,39 @@ func makeUpdateQueries(reqs []*request) []tnt.Query {
 			continue
 		}
 
-		keyTuple := make([]interface{}, 0, len(req.keys))
-		for _, key := range req.keys {
-			keyTuple = append(keyTuple, key.value)
-		}
+		queries = append(queries, makeUpdateQuery(req))
+	}
 
-		set := make([]tnt.Operator, 0, len(req.args))
-		for _, arg := range req.args {
-			set = append(set, &tnt.OpAssign{
-				Field:    arg.field,
-				Argument: arg.value,
-			})
-		}
+	return queries
+}
 
-		q := &tnt.Update{
-			Space:    req.space,
-			KeyTuple: keyTuple,
-			Set:      set,
+func makeUpdateQuery(req *request) tnt.Query {
+	// Update of a missing tuple inserts it, if the conflict policy allows it.
+	switch req.onConflict {
+	case conflictReplace:
+		return &tnt.Replace{
+			Space: req.space,
+			Tuple: makeTuple(req),
+		}
+	case conflictUpsert:
+		return &tnt.Upsert{
+			Space: req.space,
+			Tuple: makeTuple(req),
+			Set:   makeAssignOps(req),
 		}
-		queries = append(queries, q)
+	case conflictError, conflictSkip:
 	}
 
-	return queries
+	keyTuple := make([]interface{}, 0, len(req.keys))
+	for _, key := range req.keys {
+		keyTuple = append(keyTuple, key.value)
+	}
+
+	return &tnt.Update{
+		Space:    req.space,
+		KeyTuple: keyTuple,
+		Set:      makeAssignOps(req),
+	}
 }
 
 func makeDeleteQuery(req *request) tnt.Query {
@@ -99,3 +208,45 @@ func makeDeleteQueries(reqs []*request) []tnt.Query {
 
 	return queries
 }
+
+// makeTxOps converts queries to the operations accepted by applyTxExpr.
+func makeTxOps(queries []tnt.Query) ([]interface{}, error) {
+	ops := make([]interface{}, 0, len(queries))
+	for _, q := range queries {
+		var op []interface{}
+		switch v := q.(type) {
+		case *tnt.Insert:
+			op = []interface{}{"insert", v.Space, v.Tuple}
+		case *tnt.Replace:
+			op = []interface{}{"replace", v.Space, v.Tuple}
+		case *tnt.Upsert:
+			op = []interface{}{"upsert", v.Space, v.Tuple, makeTxUpdateOps(v.Set)}
+		case *tnt.Update:
+			op = []interface{}{"update", v.Space, v.KeyTuple, makeTxUpdateOps(v.Set)}
+		case *tnt.Delete:
+			op = []interface{}{"delete", v.Space, v.KeyTuple}
+		default:
+			return nil, fmt.Errorf("unsupported query in transaction: %T", q)
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// makeTxUpdateOps converts update operators to Lua ones.
+// Lua uses 1-based field numbers, the binary protocol uses 0-based.
+func makeTxUpdateOps(set []tnt.Operator) []interface{} {
+	ops := make([]interface{}, 0, len(set))
+	for _, op := range set {
+		tuple := op.AsTuple()
+		if field, ok := tuple[1].(uint64); ok {
+			tuple[1] = field + 1
+		}
+
+		ops = append(ops, tuple)
+	}
+
+	return ops
+}
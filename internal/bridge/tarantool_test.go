@@ -324,3 +324,137 @@ func (s *tarantoolSuite) TestDelete() {
 		})
 	}
 }
+
+func Test_makeTxOps(t *testing.T) {
+	queries := []tarantool.Query{
+		&tarantool.Insert{
+			Space: "users",
+			Tuple: []interface{}{uint64(1), "bob"},
+		},
+		&tarantool.Update{
+			Space:    "users",
+			KeyTuple: []interface{}{uint64(1)},
+			Set: []tarantool.Operator{
+				&tarantool.OpAssign{
+					Field:    1,
+					Argument: "alice",
+				},
+			},
+		},
+		&tarantool.Delete{
+			Space:    "users",
+			KeyTuple: []interface{}{uint64(1)},
+		},
+	}
+
+	got, err := makeTxOps(queries)
+	require.NoError(t, err)
+
+	want := []interface{}{
+		[]interface{}{"insert", "users", []interface{}{uint64(1), "bob"}},
+		[]interface{}{"update", "users", []interface{}{uint64(1)}, []interface{}{
+			[]interface{}{"=", uint64(2), "alice"},
+		}},
+		[]interface{}{"delete", "users", []interface{}{uint64(1)}},
+	}
+	assert.Equal(t, want, got)
+
+	_, err = makeTxOps([]tarantool.Query{&tarantool.Ping{}})
+	assert.Error(t, err)
+}
+
+func Test_makeQuery_OnConflict(t *testing.T) {
+	newReq := func(a action, policy conflictPolicy) *request {
+		return &request{
+			action: a,
+			space:  "users",
+			keys: []reqArg{{
+				field: 0,
+				value: uint64(1),
+			}},
+			args: []reqArg{{
+				field: 1,
+				value: "bob",
+			}},
+			onConflict: policy,
+		}
+	}
+
+	tuple := []interface{}{uint64(1), "bob"}
+	set := []tarantool.Operator{
+		&tarantool.OpAssign{
+			Field:    1,
+			Argument: "bob",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		policy     conflictPolicy
+		wantInsert tarantool.Query
+		wantUpdate tarantool.Query
+	}{
+		{
+			name:       "Error",
+			policy:     conflictError,
+			wantInsert: &tarantool.Insert{Space: "users", Tuple: tuple},
+			wantUpdate: &tarantool.Update{Space: "users", KeyTuple: []interface{}{uint64(1)}, Set: set},
+		},
+		{
+			name:       "Replace",
+			policy:     conflictReplace,
+			wantInsert: &tarantool.Replace{Space: "users", Tuple: tuple},
+			wantUpdate: &tarantool.Replace{Space: "users", Tuple: tuple},
+		},
+		{
+			name:       "Upsert",
+			policy:     conflictUpsert,
+			wantInsert: &tarantool.Upsert{Space: "users", Tuple: tuple, Set: set},
+			wantUpdate: &tarantool.Upsert{Space: "users", Tuple: tuple, Set: set},
+		},
+		{
+			name:       "Skip",
+			policy:     conflictSkip,
+			wantInsert: &tarantool.Upsert{Space: "users", Tuple: tuple, Set: []tarantool.Operator{}},
+			wantUpdate: &tarantool.Update{Space: "users", KeyTuple: []interface{}{uint64(1)}, Set: set},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := makeInsertQuery(newReq(actionInsert, tt.policy))
+			assert.Equal(t, tt.wantInsert, got)
+
+			got = makeUpdateQuery(newReq(actionUpdate, tt.policy))
+			assert.Equal(t, tt.wantUpdate, got)
+		})
+	}
+}
+
+func (s *tarantoolSuite) TestInsert_OnConflict() {
+	t := s.T()
+
+	policies := []conflictPolicy{conflictReplace, conflictUpsert, conflictSkip}
+	for _, policy := range policies {
+		for i := 0; i < 2; i++ {
+			req := &request{
+				action: actionInsert,
+				space:  "users",
+				keys: []reqArg{{
+					field: 0,
+					value: uint64(1),
+				}},
+				args: []reqArg{
+					{field: 1, value: "bob"},
+					{field: 2, value: "12345"},
+					{field: 3, value: "bob@mail.ru"},
+				},
+				onConflict: policy,
+			}
+
+			_, err := s.client.Exec(context.Background(), makeInsertQuery(req))
+			require.NoError(t, err)
+		}
+	}
+}
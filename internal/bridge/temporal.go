@@ -0,0 +1,368 @@
+package bridge
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// datetimeExtType is MessagePack extension type of Tarantool datetime.
+const datetimeExtType = 4
+
+// datetimeValue is Tarantool datetime stored in UTC.
+type datetimeValue struct {
+	seconds int64
+	nsec    int32
+}
+
+func (d *datetimeValue) ExtensionType() int8 {
+	return datetimeExtType
+}
+
+// Len returns the length of the encoded value,
+// the nanoseconds and the time zone are omitted if they are zero.
+func (d *datetimeValue) Len() int {
+	if d.nsec == 0 {
+		return 8
+	}
+
+	return 16
+}
+
+func (d *datetimeValue) MarshalBinaryTo(b []byte) error {
+	binary.LittleEndian.PutUint64(b, uint64(d.seconds))
+	if len(b) < 16 {
+		return nil
+	}
+
+	binary.LittleEndian.PutUint32(b[8:], uint32(d.nsec))
+	binary.LittleEndian.PutUint16(b[12:], 0) // tzoffset
+	binary.LittleEndian.PutUint16(b[14:], 0) // tzindex
+
+	return nil
+}
+
+func (d *datetimeValue) UnmarshalBinary(b []byte) error {
+	if len(b) != 8 && len(b) != 16 {
+		return fmt.Errorf("invalid datetime length: %d", len(b))
+	}
+
+	d.seconds = int64(binary.LittleEndian.Uint64(b))
+	d.nsec = 0
+	if len(b) == 16 {
+		d.nsec = int32(binary.LittleEndian.Uint32(b[8:]))
+	}
+
+	return nil
+}
+
+func (d *datetimeValue) String() string {
+	return time.Unix(d.seconds, int64(d.nsec)).UTC().Format(time.RFC3339Nano)
+}
+
+// temporalLayouts are the formats of DATETIME, TIMESTAMP and DATE values.
+var temporalLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+var tzOffsetRegex = regexp.MustCompile(`^([+-])(\d{2}):(\d{2})$`)
+
+// parseTimeZone returns the location of IANA time zone name, e.g. "Europe/Moscow",
+// or of the fixed offset, e.g. "+03:00". Empty name is UTC.
+func parseTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	if m := tzOffsetRegex.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("invalid time zone offset: %s", name)
+		}
+
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+
+		return time.FixedZone(name, offset), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone: %w", err)
+	}
+
+	return loc, nil
+}
+
+// isTemporalCast reports whether the cast converts the temporal values.
+func isTemporalCast(t castType) bool {
+	switch t {
+	case castUnixTimestamp, castUnixTimestampMs, castISO8601, castDatetime:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseTemporal parses DATETIME, TIMESTAMP or DATE value in the location.
+// Returns false for zero dates, e.g. "0000-00-00 00:00:00".
+func parseTemporal(i interface{}, loc *time.Location) (time.Time, bool, error) {
+	var s string
+	switch v := i.(type) {
+	case time.Time:
+		return v, true, nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return time.Time{}, false, fmt.Errorf("could not cast %T to time: %v", i, i)
+	}
+
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0000-00-00") {
+		return time.Time{}, false, nil
+	}
+
+	for _, layout := range temporalLayouts {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t, true, nil
+		}
+	}
+
+	return time.Time{}, false, &conversionError{value: s, target: "time", reason: "invalid"}
+}
+
+var timeRegex = regexp.MustCompile(`^(-)?(\d{1,3}):(\d{2}):(\d{2})(?:\.(\d{1,9}))?$`)
+
+// parseDuration parses TIME value, e.g. "-838:59:59.000000".
+func parseDuration(i interface{}) (time.Duration, error) {
+	var s string
+	switch v := i.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return 0, fmt.Errorf("could not cast %T to duration: %v", i, i)
+	}
+
+	m := timeRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, &conversionError{value: s, target: "duration", reason: "invalid"}
+	}
+
+	hours, _ := strconv.Atoi(m[2])
+	minutes, _ := strconv.Atoi(m[3])
+	seconds, _ := strconv.Atoi(m[4])
+
+	var nsec int
+	if m[5] != "" {
+		nsec, _ = strconv.Atoi((m[5] + "000000000")[:9])
+	}
+
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(nsec)
+	if m[1] == "-" {
+		d = -d
+	}
+
+	return d, nil
+}
+
+// toUnixTime casts the temporal value to the number of units since the epoch,
+// TIME value to the number of units of the duration. Zero dates are cast to null.
+func toUnixTime(i interface{}, vType attrType, loc *time.Location, unit time.Duration) (interface{}, error) {
+	switch i.(type) {
+	case string, []byte, time.Time, time.Duration:
+	default:
+		// The replacement of null, e.g. on_null: 0.
+		v, err := toInt64(i)
+		if err != nil {
+			return nil, fmt.Errorf("could not cast %T to unix time: %v", i, i)
+		}
+
+		return v, nil
+	}
+
+	if vType == typeTime {
+		d, err := parseDuration(i)
+		if err != nil {
+			return nil, err
+		}
+
+		return int64(d / unit), nil
+	}
+
+	t, ok, err := parseTemporal(i, loc)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if unit == time.Second {
+		return t.Unix(), nil
+	}
+
+	return t.Unix()*int64(time.Second/unit) + int64(t.Nanosecond())/int64(unit), nil
+}
+
+// toISO8601 formats DATE value as "2006-01-02", DATETIME and TIMESTAMP values
+// in UTC with the fractional seconds of the column, e.g. "2006-01-02T15:04:05.000Z",
+// so the strings are ordered as the values. Zero dates are cast to null.
+func toISO8601(i interface{}, vType attrType, loc *time.Location, fsp int) (interface{}, error) {
+	t, ok, err := parseTemporal(i, loc)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	if vType == typeDate {
+		return t.Format("2006-01-02"), nil
+	}
+
+	layout := "2006-01-02T15:04:05"
+	if fsp > 0 {
+		layout += "." + strings.Repeat("0", fsp)
+	}
+
+	return t.UTC().Format(layout + "Z07:00"), nil
+}
+
+// toDatetime casts the temporal value to Tarantool datetime.
+// Zero dates are cast to null.
+func toDatetime(i interface{}, loc *time.Location) (interface{}, error) {
+	t, ok, err := parseTemporal(i, loc)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &datetimeValue{
+		seconds: t.Unix(),
+		nsec:    int32(t.Nanosecond()),
+	}, nil
+}
+
+// sourceTemporal converts the value cast by the temporal cast back to the value
+// of the column in the location of the attribute, e.g. "2020-11-05 10:21:48.123".
+// Returns false if the value is not produced by the cast.
+func (a *attribute) sourceTemporal(v interface{}) (interface{}, bool) {
+	if a.vType == typeTime {
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, false
+		}
+
+		unit := time.Second
+		if a.cType == castUnixTimestampMs {
+			unit = time.Millisecond
+		}
+
+		return formatDuration(time.Duration(n) * unit), true
+	}
+
+	var t time.Time
+	switch a.cType {
+	case castUnixTimestamp:
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, false
+		}
+		t = time.Unix(n, 0)
+	case castUnixTimestampMs:
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, false
+		}
+		t = time.Unix(n/1000, n%1000*int64(time.Millisecond))
+	case castISO8601:
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		// DATE values are formatted as is.
+		if a.vType == typeDate {
+			return s, true
+		}
+
+		var err error
+		t, err = time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, false
+		}
+	case castDatetime:
+		var ok bool
+		t, ok = datetimeTime(v)
+		if !ok {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	t = t.In(a.timeZone())
+	if a.vType == typeDate {
+		return t.Format("2006-01-02"), true
+	}
+
+	return t.Format("2006-01-02 15:04:05.999999999"), true
+}
+
+// datetimeTime returns the time of the datetime written by the replicator or read from Tarantool.
+func datetimeTime(v interface{}) (time.Time, bool) {
+	var d datetimeValue
+	switch dt := v.(type) {
+	case *datetimeValue:
+		d = *dt
+	case *msgp.RawExtension:
+		if dt.Type != datetimeExtType || d.UnmarshalBinary(dt.Data) != nil {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	return time.Unix(d.seconds, int64(d.nsec)), true
+}
+
+// formatDuration formats the duration as TIME value, e.g. "-838:59:59.5".
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second)
+	if frac := d % time.Second; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", frac), "0")
+	}
+
+	return s
+}
+
+var fspRegex = regexp.MustCompile(`^(?:datetime|timestamp|time)\((\d)\)`)
+
+// fractionalDigits returns the fractional seconds precision of the temporal column type,
+// e.g. 3 for "datetime(3)".
+func fractionalDigits(rawType string) int {
+	m := fspRegex.FindStringSubmatch(strings.ToLower(rawType))
+	if m == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(m[1])
+
+	return n
+}
@@ -0,0 +1,185 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func Test_attribute_fetchValue_Temporal(t *testing.T) {
+	moscow, err := parseTimeZone("+03:00")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		vType    attrType
+		cType    castType
+		location *time.Location
+		fsp      int
+		value    interface{}
+		want     interface{}
+		wantErr  bool
+	}{
+		{name: "UnixMs_Datetime", vType: typeDatetime, cType: castUnixTimestampMs, value: "2020-11-05 10:21:48.123456", want: int64(1604571708123)},
+		{name: "UnixMs_BeforeEpoch", vType: typeDatetime, cType: castUnixTimestampMs, value: "1969-12-31 23:59:59.5", want: int64(-500)},
+		{name: "Unix_TimeZone", vType: typeDatetime, cType: castUnixTimestamp, location: moscow, value: "2020-11-05 13:21:48", want: int64(1604571708)},
+		{name: "Unix_TimestampIgnoresTimeZone", vType: typeTimestamp, cType: castUnixTimestamp, location: moscow, value: "2020-11-05 10:21:48", want: int64(1604571708)},
+		{name: "Unix_Time", vType: typeTime, cType: castUnixTimestamp, value: "-838:59:59", want: int64(-3020399)},
+		{name: "UnixMs_Time", vType: typeTime, cType: castUnixTimestampMs, value: "01:02:03.45", want: int64(3723450)},
+		{name: "Unix_InvalidTime", vType: typeTime, cType: castUnixTimestamp, value: "noon", wantErr: true},
+		{name: "ISO8601_Datetime", vType: typeDatetime, cType: castISO8601, location: moscow, value: "2020-11-05 13:21:48", want: "2020-11-05T10:21:48Z"},
+		{name: "ISO8601_Fraction", vType: typeTimestamp, cType: castISO8601, fsp: 3, value: "2020-11-05 10:21:48.1", want: "2020-11-05T10:21:48.100Z"},
+		{name: "ISO8601_Date", vType: typeDate, cType: castISO8601, location: moscow, value: "2020-11-05", want: "2020-11-05"},
+		{name: "ISO8601_ZeroDate", vType: typeDate, cType: castISO8601, value: "0000-00-00", want: nil},
+		{name: "Datetime", vType: typeDatetime, cType: castDatetime, value: "2020-11-05 10:21:48", want: &datetimeValue{seconds: 1604571708}},
+		{name: "Datetime_Fraction", vType: typeTimestamp, cType: castDatetime, value: "2020-11-05 10:21:48.123456", want: &datetimeValue{seconds: 1604571708, nsec: 123456000}},
+		{name: "Datetime_Invalid", vType: typeDatetime, cType: castDatetime, value: "2020-13-05 10:21:48", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{
+				vType:    tt.vType,
+				cType:    tt.cType,
+				location: tt.location,
+				fsp:      tt.fsp,
+			}
+
+			got, err := a.fetchValue([]interface{}{tt.value})
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_attribute_sourceValue_Temporal(t *testing.T) {
+	moscow, err := parseTimeZone("+03:00")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		vType    attrType
+		cType    castType
+		location *time.Location
+		value    string
+		key      interface{} // the key read from Tarantool instead of the cast one
+	}{
+		{name: "Unix_Datetime", vType: typeDatetime, cType: castUnixTimestamp, location: moscow, value: "2020-11-05 13:21:48"},
+		{name: "Unix_Decoded", vType: typeDatetime, cType: castUnixTimestamp, value: "2020-11-05 10:21:48", key: uint64(1604571708)},
+		{name: "Unix_Date", vType: typeDate, cType: castUnixTimestamp, location: moscow, value: "2020-11-05"},
+		{name: "Unix_Time", vType: typeTime, cType: castUnixTimestamp, value: "-838:59:59"},
+		{name: "UnixMs_Timestamp", vType: typeTimestamp, cType: castUnixTimestampMs, location: moscow, value: "2020-11-05 10:21:48.123"},
+		{name: "UnixMs_BeforeEpoch", vType: typeDatetime, cType: castUnixTimestampMs, value: "1969-12-31 23:59:59.5"},
+		{name: "UnixMs_Time", vType: typeTime, cType: castUnixTimestampMs, value: "01:02:03.45"},
+		{name: "ISO8601_Datetime", vType: typeDatetime, cType: castISO8601, location: moscow, value: "2020-11-05 13:21:48.1"},
+		{name: "ISO8601_Date", vType: typeDate, cType: castISO8601, location: moscow, value: "2020-11-05"},
+		{name: "Datetime", vType: typeDatetime, cType: castDatetime, location: moscow, value: "2020-11-05 13:21:48.123456"},
+		{
+			name:  "Datetime_Raw",
+			vType: typeTimestamp,
+			cType: castDatetime,
+			value: "2020-11-05 10:21:48",
+			key:   &msgp.RawExtension{Type: datetimeExtType, Data: []byte{0x3c, 0xd2, 0xa3, 0x5f, 0x00, 0x00, 0x00, 0x00}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			a := &attribute{
+				vType:    tt.vType,
+				cType:    tt.cType,
+				location: tt.location,
+				fsp:      6,
+			}
+
+			key := tt.key
+			if key == nil {
+				key, err = a.fetchValue([]interface{}{tt.value})
+				require.NoError(t, err)
+			}
+
+			// The key is converted back to the value selecting the row from MySQL.
+			got := a.sourceValue(key)
+			assert.Equal(t, tt.value, got)
+
+			cast, err := a.fetchValue([]interface{}{got})
+			require.NoError(t, err)
+			assert.True(t, equalTuples([]interface{}{key}, []interface{}{cast}))
+		})
+	}
+}
+
+func Test_datetimeValue_Encode(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *datetimeValue
+		want  []byte
+	}{
+		{
+			name:  "Seconds",
+			value: &datetimeValue{seconds: 1604571708},
+			want:  []byte{0xd7, 0x04, 0x3c, 0xd2, 0xa3, 0x5f, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:  "Nanoseconds",
+			value: &datetimeValue{seconds: 1604571708, nsec: 1},
+			want: []byte{
+				0xd8, 0x04, 0x3c, 0xd2, 0xa3, 0x5f, 0x00, 0x00, 0x00, 0x00,
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := msgp.AppendIntf(nil, tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// Tarantool returns the datetime as the raw extension.
+			raw, _, err := msgp.ReadIntfBytes(got)
+			require.NoError(t, err)
+			assert.True(t, equalTuples([]interface{}{tt.value}, []interface{}{raw}))
+
+			decoded := &datetimeValue{}
+			_, err = msgp.ReadExtensionBytes(got, decoded)
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, decoded)
+		})
+	}
+}
+
+func Test_parseTimeZone(t *testing.T) {
+	loc, err := parseTimeZone("")
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = parseTimeZone("-05:30")
+	require.NoError(t, err)
+	_, offset := time.Date(2020, 1, 1, 0, 0, 0, 0, loc).Zone()
+	assert.Equal(t, -(5*3600 + 30*60), offset)
+
+	_, err = parseTimeZone("+15:00")
+	assert.Error(t, err)
+
+	_, err = parseTimeZone("Mars/Olympus")
+	assert.Error(t, err)
+}
+
+func Test_fractionalDigits(t *testing.T) {
+	assert.Equal(t, 0, fractionalDigits("datetime"))
+	assert.Equal(t, 3, fractionalDigits("datetime(3)"))
+	assert.Equal(t, 6, fractionalDigits("TIMESTAMP(6)"))
+	assert.Equal(t, 0, fractionalDigits("decimal(10,2)"))
+}
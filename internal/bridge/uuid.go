@@ -0,0 +1,126 @@
+package bridge
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// uuidExtType is MessagePack extension type of Tarantool uuid.
+const uuidExtType = 2
+
+// uuidValue is Tarantool uuid, the payload is 16 bytes of the uuid.
+type uuidValue [16]byte
+
+func (u *uuidValue) ExtensionType() int8 {
+	return uuidExtType
+}
+
+func (u *uuidValue) Len() int {
+	return len(u)
+}
+
+func (u *uuidValue) MarshalBinaryTo(b []byte) error {
+	copy(b, u[:])
+
+	return nil
+}
+
+func (u *uuidValue) UnmarshalBinary(b []byte) error {
+	if len(b) != len(u) {
+		return fmt.Errorf("invalid uuid length: %d", len(b))
+	}
+
+	copy(u[:], b)
+
+	return nil
+}
+
+// String returns the canonical form of the uuid, e.g. "6ba7b810-9dad-11d1-80b4-00c04fd430c8".
+func (u *uuidValue) String() string {
+	s := hex.EncodeToString(u[:])
+
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// parseUUID parses the canonical form of the uuid.
+func parseUUID(s string) (*uuidValue, error) {
+	if !uuidRegex.MatchString(s) {
+		return nil, &conversionError{value: s, target: "uuid", reason: "invalid format"}
+	}
+
+	var u uuidValue
+	if _, err := hex.Decode(u[:], []byte(strings.ReplaceAll(s, "-", ""))); err != nil {
+		return nil, &conversionError{value: s, target: "uuid", reason: "invalid format"}
+	}
+
+	return &u, nil
+}
+
+// toUUID casts 16 bytes of BINARY column or the canonical form
+// of CHAR column to Tarantool uuid. The trailing zero bytes of BINARY(16)
+// values are trimmed in the binlog, so the values are padded to the column length.
+func toUUID(i interface{}, vType attrType, binaryLen int) (interface{}, error) {
+	var b []byte
+	switch v := i.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return nil, fmt.Errorf("could not cast %T to uuid: %v", i, i)
+	}
+
+	if vType != typeBinary || len(b) == 36 {
+		return parseUUID(string(b))
+	}
+
+	if binaryLen == 16 && len(b) < 16 {
+		padded := make([]byte, 16)
+		copy(padded, b)
+		b = padded
+	}
+	if len(b) != 16 {
+		return nil, &conversionError{value: hex.EncodeToString(b), target: "uuid", reason: "must be 16 bytes"}
+	}
+
+	var u uuidValue
+	copy(u[:], b)
+
+	return &u, nil
+}
+
+// uuidBytes returns the bytes of the uuid written by the replicator or read from Tarantool.
+func uuidBytes(v interface{}) ([]byte, bool) {
+	switch u := v.(type) {
+	case *uuidValue:
+		return u[:], true
+	case *msgp.RawExtension:
+		if u.Type == uuidExtType && len(u.Data) == 16 {
+			return u.Data, true
+		}
+	}
+
+	return nil, false
+}
+
+var binaryLenRegex = regexp.MustCompile(`^binary\((\d+)\)`)
+
+// binaryLength returns the length of BINARY column type, e.g. 16 for "binary(16)",
+// zero for the other types.
+func binaryLength(rawType string) int {
+	m := binaryLenRegex.FindStringSubmatch(strings.ToLower(rawType))
+	if m == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(m[1])
+
+	return n
+}
@@ -0,0 +1,124 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/siddontang/go-mysql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+var testUUID = uuidValue{
+	0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1,
+	0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8,
+}
+
+func Test_toUUID(t *testing.T) {
+	padded := testUUID
+	padded[14], padded[15] = 0, 0
+
+	tests := []struct {
+		name      string
+		vType     attrType
+		binaryLen int
+		value     interface{}
+		want      *uuidValue
+		wantErr   bool
+	}{
+		{name: "Binary", vType: typeBinary, binaryLen: 16, value: string(testUUID[:]), want: &testUUID},
+		{name: "BinaryBytes", vType: typeBinary, binaryLen: 16, value: testUUID[:], want: &testUUID},
+		{name: "BinaryTrimmed", vType: typeBinary, binaryLen: 16, value: string(padded[:14]), want: &padded},
+		{name: "BinaryText", vType: typeBinary, binaryLen: 36, value: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", want: &testUUID},
+		{name: "VarbinaryShort", vType: typeBinary, value: string(testUUID[:15]), wantErr: true},
+		{name: "BinaryLong", vType: typeBinary, binaryLen: 20, value: string(testUUID[:]) + "1234", wantErr: true},
+		{name: "Char", vType: typeString, value: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", want: &testUUID},
+		{name: "CharUpperCase", vType: typeString, value: "6BA7B810-9DAD-11D1-80B4-00C04FD430C8", want: &testUUID},
+		{name: "CharNoDashes", vType: typeString, value: "6ba7b8109dad11d180b400c04fd430c8", wantErr: true},
+		{name: "CharInvalidDigit", vType: typeString, value: "6ba7b810-9dad-11d1-80b4-00c04fd430cz", wantErr: true},
+		{name: "CharShort", vType: typeString, value: "6ba7b810-9dad-11d1-80b4", wantErr: true},
+		{name: "Number", vType: typeString, value: int64(1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toUUID(tt.value, tt.vType, tt.binaryLen)
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_uuidValue_Encode(t *testing.T) {
+	got, err := msgp.AppendIntf(nil, &testUUID)
+	require.NoError(t, err)
+	assert.Equal(t, append([]byte{0xd8, 0x02}, testUUID[:]...), got)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", testUUID.String())
+
+	// Tarantool returns the uuid as the raw extension.
+	raw, _, err := msgp.ReadIntfBytes(got)
+	require.NoError(t, err)
+	assert.True(t, equalTuples([]interface{}{&testUUID}, []interface{}{raw}))
+}
+
+func Test_makeRequests_UUIDKey(t *testing.T) {
+	table := &schema.Table{
+		Schema: "city",
+		Name:   "sessions",
+	}
+	table.AddColumn("id", "binary(16)", "", "")
+	table.AddColumn("user_id", "char(36)", "", "")
+	table.PKColumns = []int{0}
+
+	mapping := newTestMapping("sessions", []string{"user_id"}, nil)
+	mapping.Dest.Column = map[string]config.MappingColumn{
+		"id":      {Cast: "uuid"},
+		"user_id": {Cast: "uuid"},
+	}
+
+	r, err := newRule(mapping, table)
+	require.NoError(t, err)
+
+	other := testUUID
+	other[0] = 0
+
+	before := []interface{}{string(testUUID[:]), "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}
+	same := []interface{}{string(testUUID[:]), "00a7b810-9dad-11d1-80b4-00c04fd430c8"}
+	moved := []interface{}{string(other[:]), "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}
+
+	reqs, err := makeRequests(r, "update", [][]interface{}{before, same})
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, actionUpdate, reqs[0].action)
+	assert.Equal(t, []reqArg{{field: 0, value: &testUUID}}, reqs[0].keys)
+	assert.Equal(t, []reqArg{{field: 1, value: &other}}, reqs[0].args)
+
+	// The changed uuid key deletes the old tuple and inserts the new one.
+	reqs, err = makeRequests(r, "update", [][]interface{}{before, moved})
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	assert.Equal(t, actionDelete, reqs[0].action)
+	assert.Equal(t, actionInsert, reqs[1].action)
+
+	_, err = makeRequests(r, "insert", [][]interface{}{{string(testUUID[:]), "not a uuid"}})
+	assert.Error(t, err)
+
+	// The tuple keys are converted back to select the rows from MySQL.
+	raw := &msgp.RawExtension{Type: uuidExtType, Data: testUUID[:]}
+	assert.Equal(t, string(testUUID[:]), r.pks[0].sourceValue(raw))
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", r.attrs[0].sourceValue(raw))
+}
+
+func Test_binaryLength(t *testing.T) {
+	assert.Equal(t, 16, binaryLength("binary(16)"))
+	assert.Equal(t, 0, binaryLength("varbinary(16)"))
+	assert.Equal(t, 0, binaryLength("char(36)"))
+}
@@ -0,0 +1,363 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/siddontang/go-mysql/mysql"
+	tnt "github.com/viciious/go-tarantool"
+)
+
+// spaceSchemaExpr returns {exists, format, primary index parts} of the space.
+// Each field is {name, type, is_nullable}, each part is {fieldno, type, is_nullable}.
+const spaceSchemaExpr = `
+local name = ...
+local space = box.space[name]
+if space == nil then
+    return {false}
+end
+
+local format = {}
+for i, f in ipairs(space:format()) do
+    format[i] = {f.name or '', f.type or 'any', f.is_nullable == true}
+end
+
+local parts = {}
+if space.index[0] ~= nil then
+    for i, p in ipairs(space.index[0].parts) do
+        parts[i] = {p.fieldno, p.type, p.is_nullable == true}
+    end
+end
+
+return {true, format, parts}
+`
+
+const nullableColumnsQuery = `
+SELECT COLUMN_NAME
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND IS_NULLABLE = 'YES'
+`
+
+type spaceField struct {
+	name      string
+	fieldType string
+	nullable  bool
+}
+
+type spacePart struct {
+	field     uint64 // zero-based
+	fieldType string
+}
+
+// spaceSchema is the format and the primary index of Tarantool space.
+type spaceSchema struct {
+	format []spaceField
+	pk     []spacePart
+}
+
+// validateSpaces compares the rules with the formats and the primary indexes
+// of their spaces. Returns the error listing every incompatibility.
+func (b *Bridge) validateSpaces() error {
+	var issues []string
+
+	schemas := make(map[string]*spaceSchema)
+	for _, rules := range b.tables() {
+		nullable, err := b.nullableColumns(rules[0].schema, rules[0].table)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rules {
+			sch, ok := schemas[r.space]
+			if !ok {
+				sch, err = b.fetchSpaceSchema(r.space)
+				if err != nil {
+					return err
+				}
+
+				schemas[r.space] = sch
+			}
+
+			issues = append(issues, validateRule(r, sch, nullable)...)
+		}
+	}
+
+	if len(issues) == 0 {
+		return nil
+	}
+
+	sort.Strings(issues)
+
+	return fmt.Errorf("mappings do not match Tarantool spaces:\n\t%s", strings.Join(issues, "\n\t"))
+}
+
+func (b *Bridge) nullableColumns(schema, table string) (map[string]bool, error) {
+	return queryNullableColumns(b.canal, schema, table)
+}
+
+func queryNullableColumns(conn mysql.Executer, schema, table string) (map[string]bool, error) {
+	res, err := conn.Execute(nullableColumnsQuery, schema, table)
+	if err != nil {
+		return nil, err
+	}
+
+	nullable := make(map[string]bool, res.RowNumber())
+	for i := 0; i < res.RowNumber(); i++ {
+		name, err := res.GetString(i, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		nullable[name] = true
+	}
+
+	return nullable, nil
+}
+
+// fetchSpaceSchema returns nil if the space does not exist.
+func (b *Bridge) fetchSpaceSchema(space string) (*spaceSchema, error) {
+	res, err := b.tntClient.Exec(context.Background(), &tnt.Eval{
+		Expression: spaceSchemaExpr,
+		Tuple:      []interface{}{space},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch format of space %s: %w", space, err)
+	}
+
+	if len(res.Data) == 0 {
+		return nil, fmt.Errorf("could not fetch format of space %s: empty result", space)
+	}
+
+	sch, err := parseSpaceSchema(res.Data[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse format of space %s: %w", space, err)
+	}
+
+	return sch, nil
+}
+
+func parseSpaceSchema(data []interface{}) (*spaceSchema, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("unexpected result: %v", data)
+	}
+
+	if exists, _ := data[0].(bool); !exists {
+		return nil, nil
+	}
+
+	if len(data) != 3 {
+		return nil, fmt.Errorf("unexpected result: %v", data)
+	}
+
+	format, err := parseSpaceList(data[1])
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := parseSpaceList(data[2])
+	if err != nil {
+		return nil, err
+	}
+
+	sch := &spaceSchema{
+		format: make([]spaceField, 0, len(format)),
+		pk:     make([]spacePart, 0, len(parts)),
+	}
+	for _, f := range format {
+		name, _ := f[0].(string)
+		fieldType, _ := f[1].(string)
+		nullable, _ := f[2].(bool)
+		sch.format = append(sch.format, spaceField{
+			name:      name,
+			fieldType: fieldType,
+			nullable:  nullable,
+		})
+	}
+	for _, p := range parts {
+		fieldNo, err := toUint64(p[0])
+		if err != nil || fieldNo == 0 {
+			return nil, fmt.Errorf("invalid field number of the primary index part: %v", p[0])
+		}
+
+		fieldType, _ := p[1].(string)
+		sch.pk = append(sch.pk, spacePart{
+			field:     fieldNo - 1,
+			fieldType: fieldType,
+		})
+	}
+
+	return sch, nil
+}
+
+// parseSpaceList parses the list of fields or index parts, each item has 3 values.
+func parseSpaceList(v interface{}) ([][]interface{}, error) {
+	// Empty Lua table is decoded as a map.
+	if m, ok := v.(map[interface{}]interface{}); ok && len(m) == 0 {
+		return nil, nil
+	}
+
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected list: %v", v)
+	}
+
+	items := make([][]interface{}, 0, len(list))
+	for _, item := range list {
+		values, ok := item.([]interface{})
+		if !ok || len(values) != 3 {
+			return nil, fmt.Errorf("unexpected item: %v", item)
+		}
+
+		items = append(items, values)
+	}
+
+	return items, nil
+}
+
+// validateRule returns the incompatibilities of the rule and the space.
+// Nullable contains the nullable columns of the table.
+func validateRule(r *rule, sch *spaceSchema, nullable map[string]bool) []string {
+	prefix := fmt.Sprintf("%s.%s -> %s:", r.schema, r.table, r.space)
+	if sch == nil {
+		return []string{fmt.Sprintf("%s space does not exist", prefix)}
+	}
+
+	var issues []string
+
+	attrs := make([]*attribute, 0, len(r.pks)+len(r.attrs))
+	attrs = append(attrs, r.pks...)
+	attrs = append(attrs, r.attrs...)
+	for _, attr := range attrs {
+		if attr.tupIndex >= uint64(len(sch.format)) {
+			continue
+		}
+
+		f := sch.format[attr.tupIndex]
+		if !fieldTypeCompatible(attr, f.fieldType) {
+			issues = append(issues, fmt.Sprintf("%s field %d %q has type %s, but column %s is %s",
+				prefix, attr.tupIndex+1, f.name, f.fieldType, attr.name, describeColumn(r, attr)))
+		}
+		if isNullable(attr, nullable) && !f.nullable {
+			issues = append(issues, fmt.Sprintf("%s field %d %q is not nullable, but column %s is nullable and on_null is not set",
+				prefix, attr.tupIndex+1, f.name, attr.name))
+		}
+	}
+
+	for i := len(attrs); i < len(sch.format); i++ {
+		f := sch.format[i]
+		if !f.nullable {
+			issues = append(issues, fmt.Sprintf("%s field %d %q is not nullable, but no column is mapped to it",
+				prefix, i+1, f.name))
+		}
+	}
+
+	if len(sch.pk) != len(r.pks) {
+		issues = append(issues, fmt.Sprintf("%s primary index has %d parts, but the key has %d columns",
+			prefix, len(sch.pk), len(r.pks)))
+
+		return issues
+	}
+
+	for i, pk := range r.pks {
+		part := sch.pk[i]
+		if part.field != pk.tupIndex {
+			issues = append(issues, fmt.Sprintf("%s primary index part %d is field %d, but key column %s is field %d",
+				prefix, i+1, part.field+1, pk.name, pk.tupIndex+1))
+		}
+		if !fieldTypeCompatible(pk, part.fieldType) {
+			issues = append(issues, fmt.Sprintf("%s primary index part %d has type %s, but key column %s is %s",
+				prefix, i+1, part.fieldType, pk.name, describeColumn(r, pk)))
+		}
+	}
+
+	return issues
+}
+
+// fieldTypeCompatible reports whether the values of the attribute
+// may be stored in the field of Tarantool type.
+func fieldTypeCompatible(attr *attribute, fieldType string) bool {
+	switch fieldType {
+	case "", "any", "scalar":
+		return true
+	}
+
+	allowed := vTypeFieldTypes(attr)
+	switch {
+	case attr.path != nil:
+		allowed = jsonFieldTypes
+	case attr.cType != castNone:
+		allowed = castFieldTypes[attr.cType]
+	}
+
+	for _, t := range allowed {
+		if t == fieldType {
+			return true
+		}
+	}
+
+	return allowed == nil
+}
+
+// jsonFieldTypes are Tarantool field types storing the values extracted
+// from JSON documents, the type of the extracted value is not known in advance.
+var jsonFieldTypes = []string{"unsigned", "integer", "number", "double", "string", "boolean", "map", "array"}
+
+// castFieldTypes are Tarantool field types storing the cast values,
+// the most specific type goes first.
+var castFieldTypes = map[castType][]string{
+	castUnsigned:        {"unsigned", "integer", "number"},
+	castInteger:         {"integer", "number"},
+	castNumber:          {"number"},
+	castDouble:          {"double", "number"},
+	castBoolean:         {"boolean"},
+	castString:          {"string"},
+	castUnixTimestamp:   {"integer", "number"},
+	castUnixTimestampMs: {"integer", "number"},
+	castISO8601:         {"string"},
+	castDatetime:        {"datetime"},
+	castJSON:            {"any", "map", "array"},
+	castDecimal:         {"decimal", "number"},
+	castDecimalString:   {"string"},
+	castUUID:            {"uuid"},
+	castArray:           {"array"},
+	castMap:             {"map"},
+}
+
+// vTypeFieldTypes returns Tarantool field types storing the values
+// of the column as is, nil if the type is not known.
+func vTypeFieldTypes(attr *attribute) []string {
+	var allowed []string
+	switch attr.vType {
+	case typeNumber, typeMediumInt, typeEnum, typeSet, typeBit:
+		if attr.unsigned {
+			allowed = []string{"unsigned", "integer", "number"}
+		} else {
+			allowed = []string{"integer", "number"}
+		}
+	case typeFloat, typeDecimal:
+		allowed = []string{"number", "double"}
+	case typeString, typeDatetime, typeTimestamp, typeDate, typeTime:
+		allowed = []string{"string"}
+	case typeJSON, typeBinary:
+		allowed = []string{"string", "varbinary"}
+	}
+
+	return allowed
+}
+
+// describeColumn returns MySQL type of the attribute column and its cast.
+func describeColumn(r *rule, attr *attribute) string {
+	desc := "unknown"
+	if r.tableInfo != nil && attr.colIndex < uint64(len(r.tableInfo.Columns)) {
+		desc = r.tableInfo.Columns[attr.colIndex].RawType
+	}
+	if attr.path != nil {
+		desc += " extracted to " + attr.field
+	} else if attr.cType != castNone {
+		desc += " cast to " + attr.cType.String()
+	}
+
+	return desc
+}
@@ -0,0 +1,247 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/siddontang/go-mysql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pparshin/go-mysql-tarantool/internal/config"
+)
+
+func newTestUsersSchema() *spaceSchema {
+	return &spaceSchema{
+		format: []spaceField{
+			{name: "id", fieldType: "unsigned"},
+			{name: "username", fieldType: "string"},
+			{name: "email", fieldType: "string", nullable: true},
+		},
+		pk: []spacePart{
+			{field: 0, fieldType: "unsigned"},
+		},
+	}
+}
+
+func Test_parseSpaceSchema(t *testing.T) {
+	got, err := parseSpaceSchema([]interface{}{false})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = parseSpaceSchema([]interface{}{
+		true,
+		[]interface{}{
+			[]interface{}{"id", "unsigned", false},
+			[]interface{}{"username", "string", false},
+			[]interface{}{"email", "string", true},
+		},
+		[]interface{}{
+			[]interface{}{uint64(1), "unsigned", false},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, newTestUsersSchema(), got)
+
+	// Space without format.
+	got, err = parseSpaceSchema([]interface{}{
+		true,
+		map[interface{}]interface{}{},
+		[]interface{}{
+			[]interface{}{int64(1), "unsigned", false},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, got.format)
+	assert.Equal(t, []spacePart{{field: 0, fieldType: "unsigned"}}, got.pk)
+
+	_, err = parseSpaceSchema([]interface{}{
+		true,
+		[]interface{}{},
+		[]interface{}{
+			[]interface{}{int64(0), "unsigned", false},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func Test_validateRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		schema     func() *spaceSchema
+		columns    []string
+		nullable   map[string]bool
+		onNull     interface{}
+		wantIssues int
+	}{
+		{
+			name:    "Compatible",
+			schema:  newTestUsersSchema,
+			columns: []string{"username", "email"},
+		},
+		{
+			name:       "SpaceNotExist",
+			schema:     func() *spaceSchema { return nil },
+			columns:    []string{"username", "email"},
+			wantIssues: 1,
+		},
+		{
+			name: "TypeMismatch",
+			schema: func() *spaceSchema {
+				sch := newTestUsersSchema()
+				sch.format[1].fieldType = "unsigned"
+
+				return sch
+			},
+			columns:    []string{"username", "email"},
+			wantIssues: 1,
+		},
+		{
+			name: "NotNullableField",
+			schema: func() *spaceSchema {
+				sch := newTestUsersSchema()
+				sch.format[2].nullable = false
+
+				return sch
+			},
+			columns:    []string{"username", "email"},
+			nullable:   map[string]bool{"email": true},
+			wantIssues: 1,
+		},
+		{
+			name: "NotNullableFieldWithOnNull",
+			schema: func() *spaceSchema {
+				sch := newTestUsersSchema()
+				sch.format[2].nullable = false
+
+				return sch
+			},
+			columns:  []string{"username", "email"},
+			nullable: map[string]bool{"email": true},
+			onNull:   "",
+		},
+		{
+			name: "UnmappedRequiredField",
+			schema: func() *spaceSchema {
+				sch := newTestUsersSchema()
+				sch.format[2].nullable = false
+
+				return sch
+			},
+			columns:    []string{"username"},
+			wantIssues: 1,
+		},
+		{
+			name: "PrimaryIndexMismatch",
+			schema: func() *spaceSchema {
+				sch := newTestUsersSchema()
+				sch.pk = []spacePart{{field: 1, fieldType: "string"}}
+
+				return sch
+			},
+			columns:    []string{"username", "email"},
+			wantIssues: 2,
+		},
+		{
+			name: "PrimaryIndexParts",
+			schema: func() *spaceSchema {
+				sch := newTestUsersSchema()
+				sch.pk = append(sch.pk, spacePart{field: 1, fieldType: "string"})
+
+				return sch
+			},
+			columns:    []string{"username", "email"},
+			wantIssues: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := newTestMapping("users", tt.columns, nil)
+			r, err := newRule(mapping, newTestUsersTable())
+			require.NoError(t, err)
+
+			for _, attr := range r.attrs {
+				attr.onNull = tt.onNull
+			}
+
+			issues := validateRule(r, tt.schema(), tt.nullable)
+			assert.Len(t, issues, tt.wantIssues, issues)
+		})
+	}
+}
+
+func Test_validateRule_Extracted(t *testing.T) {
+	table := &schema.Table{
+		Schema: "city",
+		Name:   "profiles",
+	}
+	table.AddColumn("id", "int(11) unsigned", "", "")
+	table.AddColumn("attrs", "json", "", "")
+	table.AddColumn("name", "varchar(64)", "", "")
+	table.PKColumns = []int{0}
+
+	mapping := newTestMapping("profiles", []string{"attrs", "name"}, nil)
+	mapping.Dest.Column = map[string]config.MappingColumn{
+		"attrs": {
+			Cast: "json",
+			Extract: []config.MappingJSONPath{
+				{Field: "age", Path: "$.age"},
+				{Field: "city", Path: "$.address.city"},
+			},
+		},
+	}
+
+	r, err := newRule(mapping, table)
+	require.NoError(t, err)
+
+	sch := &spaceSchema{
+		format: []spaceField{
+			{name: "id", fieldType: "unsigned"},
+			{name: "attrs", fieldType: "map", nullable: true},
+			{name: "age", fieldType: "integer", nullable: true},
+			{name: "city", fieldType: "string", nullable: true},
+			{name: "name", fieldType: "string", nullable: true},
+		},
+		pk: []spacePart{
+			{field: 0, fieldType: "unsigned"},
+		},
+	}
+
+	issues := validateRule(r, sch, nil)
+	assert.Empty(t, issues)
+
+	sch.format[3].fieldType = "uuid"
+	issues = validateRule(r, sch, nil)
+	assert.Len(t, issues, 1, issues)
+}
+
+func Test_fieldTypeCompatible(t *testing.T) {
+	tests := []struct {
+		attr      *attribute
+		fieldType string
+		want      bool
+	}{
+		{attr: &attribute{vType: typeNumber, unsigned: true}, fieldType: "unsigned", want: true},
+		{attr: &attribute{vType: typeNumber}, fieldType: "unsigned", want: false},
+		{attr: &attribute{vType: typeNumber, cType: castUnsigned}, fieldType: "unsigned", want: true},
+		{attr: &attribute{vType: typeNumber}, fieldType: "integer", want: true},
+		{attr: &attribute{vType: typeNumber}, fieldType: "string", want: false},
+		{attr: &attribute{vType: typeFloat}, fieldType: "number", want: true},
+		{attr: &attribute{vType: typeFloat}, fieldType: "integer", want: false},
+		{attr: &attribute{vType: typeString}, fieldType: "string", want: true},
+		{attr: &attribute{vType: typeString}, fieldType: "scalar", want: true},
+		{attr: &attribute{vType: typeDatetime}, fieldType: "unsigned", want: false},
+		{attr: &attribute{vType: typeBinary}, fieldType: "varbinary", want: true},
+		{attr: &attribute{vType: typePoint}, fieldType: "array", want: true},
+		{attr: &attribute{vType: typeJSON, cType: castJSON}, fieldType: "integer", want: false},
+		{attr: &attribute{vType: typeJSON, cType: castJSON, path: []jsonPathStep{{key: "age"}}}, fieldType: "integer", want: true},
+		{attr: &attribute{vType: typeJSON, cType: castJSON, path: []jsonPathStep{{key: "name"}}}, fieldType: "string", want: true},
+		{attr: &attribute{vType: typeJSON, cType: castJSON, path: []jsonPathStep{{key: "id"}}}, fieldType: "uuid", want: false},
+	}
+
+	for _, tt := range tests {
+		got := fieldTypeCompatible(tt.attr, tt.fieldType)
+		assert.Equal(t, tt.want, got, "%+v %s", tt.attr, tt.fieldType)
+	}
+}
@@ -0,0 +1,835 @@
+package bridge
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // not used for security
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+	"github.com/siddontang/go-mysql/client"
+	"github.com/tinylib/msgp/msgp"
+	tnt "github.com/viciious/go-tarantool"
+)
+
+// maxReportedKeys limits the number of primary keys kept in the report per kind of difference.
+const maxReportedKeys = 1000
+
+const (
+	// getTuplesExpr returns the tuples by the list of keys,
+	// the missing tuples are returned as nulls.
+	getTuplesExpr = `
+local name, keys = ...
+local space = box.space[name]
+if space == nil then
+    box.error(box.error.NO_SUCH_SPACE, name)
+end
+local res = {}
+for i, key in ipairs(keys) do
+    local t = space:get(key)
+    if t == nil then
+        res[i] = box.NULL
+    else
+        res[i] = t
+    end
+end
+return res
+`
+
+	// rangeChecksumExpr returns {checksum, count} of the tuples of the primary key range
+	// starting at the first key, at most limit tuples up to the last key are included.
+	// The fields are written the same way as by writeValue, at most width fields of each tuple.
+	rangeChecksumExpr = `
+local name, first, last, limit, width = ...
+local space = box.space[name]
+if space == nil then
+    box.error(box.error.NO_SUCH_SPACE, name)
+end
+local ffi = require('ffi')
+local digest = require('digest')
+local uuid = require('uuid')
+local has_decimal, decimal = pcall(require, 'decimal')
+
+local function is_array(v)
+    local mt = getmetatable(v)
+    if mt ~= nil and mt.__serialize ~= nil then
+        return mt.__serialize == 'seq' or mt.__serialize == 'sequence' or mt.__serialize == 'array'
+    end
+    local n = 0
+    for _ in pairs(v) do
+        n = n + 1
+    end
+    return n == #v
+end
+
+local write
+write = function(buf, v)
+    if v == nil then
+        table.insert(buf, 'n')
+    elseif type(v) == 'number' then
+        if v ~= v or v ~= math.floor(v) or math.abs(v) >= 2^53 then
+            table.insert(buf, 'f' .. string.format('%.17g', v))
+        elseif v == 0 then
+            table.insert(buf, 'u0')
+        elseif v > 0 then
+            table.insert(buf, 'u' .. string.format('%.0f', v))
+        else
+            table.insert(buf, 'i' .. string.format('%.0f', v))
+        end
+    elseif type(v) == 'string' then
+        table.insert(buf, 's' .. #v .. ':' .. v)
+    elseif type(v) == 'boolean' then
+        table.insert(buf, 'b' .. tostring(v))
+    elseif ffi.istype('uint64_t', v) then
+        table.insert(buf, 'u' .. tostring(v):sub(1, -4))
+    elseif ffi.istype('int64_t', v) then
+        table.insert(buf, (v < 0 and 'i' or 'u') .. tostring(v):sub(1, -3))
+    elseif has_decimal and decimal.is_decimal(v) then
+        local s = tostring(v)
+        if s:find('.', 1, true) then
+            s = s:gsub('0+$', ''):gsub('%.$', '')
+        end
+        table.insert(buf, 'd' .. s)
+    elseif uuid.is_uuid ~= nil and uuid.is_uuid(v) then
+        table.insert(buf, 'x2:' .. v:str():gsub('-', ''))
+    elseif type(v) == 'table' and is_array(v) then
+        table.insert(buf, 'a[')
+        for i, e in ipairs(v) do
+            if i > 1 then
+                table.insert(buf, ',')
+            end
+            write(buf, e)
+        end
+        table.insert(buf, ']')
+    elseif type(v) == 'table' then
+        local keys = {}
+        for k in pairs(v) do
+            if type(k) ~= 'string' then
+                table.insert(buf, '?')
+                return
+            end
+            table.insert(keys, k)
+        end
+        table.sort(keys)
+        table.insert(buf, 'm{')
+        for i, k in ipairs(keys) do
+            if i > 1 then
+                table.insert(buf, ',')
+            end
+            write(buf, k)
+            table.insert(buf, ':')
+            write(buf, v[k])
+        end
+        table.insert(buf, '}')
+    else
+        -- Never equal to the values written by the replicator.
+        table.insert(buf, '?' .. tostring(v))
+    end
+end
+
+local parts = space.index[0].parts
+local buf = {}
+local count = 0
+for _, t in space.index[0]:pairs(first, { iterator = 'GE' }) do
+    if count >= limit then
+        break
+    end
+    if count > 0 then
+        table.insert(buf, ';')
+    end
+    for i = 1, math.min(#t, width) do
+        if i > 1 then
+            table.insert(buf, ',')
+        end
+        write(buf, t[i])
+    end
+    count = count + 1
+
+    local reached = true
+    for i, part in ipairs(parts) do
+        if t[part.fieldno] ~= last[i] then
+            reached = false
+            break
+        end
+    end
+    if reached then
+        break
+    end
+end
+return { digest.md5_hex(table.concat(buf)), count }
+`
+
+	// selectKeysExpr returns the primary keys of the tuples following the given key.
+	selectKeysExpr = `
+local name, after, limit = ...
+local space = box.space[name]
+if space == nil then
+    box.error(box.error.NO_SUCH_SPACE, name)
+end
+local parts = space.index[0].parts
+local res = {}
+for _, t in space.index[0]:pairs(after, { iterator = 'GT' }) do
+    if #res >= limit then
+        break
+    end
+    local key = {}
+    for i, part in ipairs(parts) do
+        key[i] = t[part.fieldno]
+    end
+    table.insert(res, key)
+end
+return res
+`
+)
+
+// VerifyReport is the result of comparison of the MySQL table
+// with the Tarantool space.
+type VerifyReport struct {
+	Schema string
+	Table  string
+	Space  string
+
+	Rows   uint64 // number of checked MySQL rows
+	Tuples uint64 // number of checked Tarantool tuples
+
+	// Number of tuples missing in Tarantool, absent in MySQL or having different values.
+	MissingCount uint64
+	ExtraCount   uint64
+	DifferCount  uint64
+
+	// Primary keys of the divergent tuples, at most maxReportedKeys of each kind.
+	Missing [][]interface{}
+	Extra   [][]interface{}
+	Differ  [][]interface{}
+}
+
+// MarshalZerologObject adds the report fields to the log event.
+func (r *VerifyReport) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("schema", r.Schema).
+		Str("table", r.Table).
+		Str("space", r.Space).
+		Uint64("rows", r.Rows).
+		Uint64("tuples", r.Tuples).
+		Uint64("missing", r.MissingCount).
+		Uint64("extra", r.ExtraCount).
+		Uint64("differ", r.DifferCount).
+		Interface("missing_keys", r.Missing).
+		Interface("extra_keys", r.Extra).
+		Interface("differ_keys", r.Differ)
+}
+
+// Consistent returns true if no difference is found.
+func (r *VerifyReport) Consistent() bool {
+	return r.MissingCount == 0 && r.ExtraCount == 0 && r.DifferCount == 0
+}
+
+func (r *VerifyReport) addMissing(key []interface{}) {
+	r.MissingCount++
+	if len(r.Missing) < maxReportedKeys {
+		r.Missing = append(r.Missing, key)
+	}
+}
+
+func (r *VerifyReport) addExtra(key []interface{}) {
+	r.ExtraCount++
+	if len(r.Extra) < maxReportedKeys {
+		r.Extra = append(r.Extra, key)
+	}
+}
+
+func (r *VerifyReport) addDiffer(key []interface{}) {
+	r.DifferCount++
+	if len(r.Differ) < maxReportedKeys {
+		r.Differ = append(r.Differ, key)
+	}
+}
+
+// Verify compares each mapped MySQL table with its Tarantool space.
+//
+// MySQL rows are read by primary key ranges and converted to tuples
+// the same way as replicated ones, the checksum of each range is compared
+// with the checksum of the stored tuples computed in Tarantool, the tuples
+// of the ranges with different checksums are fetched and compared by key.
+// Then the space is walked
+// to find tuples absent in MySQL, unless the space is shared by several tables.
+//
+// Rows changed during the verification may be reported as divergent.
+func (b *Bridge) Verify(ctx context.Context) ([]*VerifyReport, error) {
+	conn, err := b.connectSource()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	tables := b.tables()
+	reports := make([]*VerifyReport, 0, len(tables))
+	for _, rules := range tables {
+		for _, r := range rules {
+			report, err := b.verifyRule(ctx, conn, r, false)
+			if err != nil {
+				return nil, fmt.Errorf("verify %s.%s: %w", r.schema, r.table, err)
+			}
+
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+// verifyRule compares the table with the space, the divergent tuples
+// are sent to sync to be repaired if repair is true.
+func (b *Bridge) verifyRule(ctx context.Context, conn *client.Conn, r *rule, repair bool) (*VerifyReport, error) {
+	report := &VerifyReport{
+		Schema: r.schema,
+		Table:  r.table,
+		Space:  r.space,
+	}
+
+	table := r.tableInfo
+	chunkSize := b.snapshotCfg.chunkSize
+
+	var last []interface{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		res, err := conn.Execute(buildChunkQuery(table, chunkSize, last != nil), last...)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(res.Values) == 0 {
+			break
+		}
+
+		rows := make([][]interface{}, 0, len(res.Values))
+		for _, values := range res.Values {
+			row, err := snapshotRow(table, values)
+			if err != nil {
+				return nil, err
+			}
+
+			rows = append(rows, row)
+		}
+
+		divergent, err := b.verifyRange(ctx, r, rows, report)
+		if err != nil {
+			return nil, err
+		}
+
+		if repair && len(divergent) > 0 {
+			msg, err := b.readRepair(conn, r, divergent)
+			if err != nil {
+				return nil, err
+			}
+
+			b.syncCh <- msg
+		}
+
+		if len(rows) < chunkSize {
+			break
+		}
+
+		last = chunkKey(table, rows[len(rows)-1])
+	}
+
+	// Tuples of the shared space may come from other tables.
+	if b.sharedSpace(r.space) {
+		b.logger.Warn().
+			Str("space", r.space).
+			Msg("space is shared by several tables, skip searching extra tuples")
+
+		return report, nil
+	}
+
+	err := b.verifyExtra(ctx, conn, r, report, repair)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// verifyRange compares the rows of the primary key range with the stored tuples
+// and returns the keys of the divergent tuples.
+func (b *Bridge) verifyRange(ctx context.Context, r *rule, rows [][]interface{}, report *VerifyReport) ([][]interface{}, error) {
+	expected := make([][]interface{}, 0, len(rows))
+	keys := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		// The filtered out rows are not replicated.
+		if !r.match(row) {
+			continue
+		}
+
+		req, err := makeInsertRequest(r, row)
+		if err != nil {
+			return nil, err
+		}
+		if req == nil {
+			continue
+		}
+
+		tuple := makeTuple(req)
+		expected = append(expected, tuple)
+		keys = append(keys, tuple[:len(r.pks)])
+	}
+
+	report.Rows += uint64(len(expected))
+
+	if len(expected) == 0 {
+		return nil, nil
+	}
+
+	// The tuples are fetched only if the checksums of the range differ.
+	equal, err := b.equalRangeChecksum(ctx, r, expected, keys)
+	if err != nil {
+		return nil, err
+	}
+	if equal {
+		report.Tuples += uint64(len(expected))
+
+		return nil, nil
+	}
+
+	res, err := b.tntClient.Exec(ctx, &tnt.Eval{
+		Expression: getTuplesExpr,
+		Tuple:      []interface{}{r.space, keys},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actual := fetchedTuples(res, expected)
+
+	var divergent [][]interface{}
+	for i, want := range expected {
+		got := actual[i]
+		key := want[:len(r.pks)]
+
+		switch {
+		case got == nil:
+			report.addMissing(key)
+			divergent = append(divergent, key)
+		case !equalTuples(want, got):
+			report.Tuples++
+			report.addDiffer(key)
+			divergent = append(divergent, key)
+		default:
+			report.Tuples++
+		}
+	}
+
+	return divergent, nil
+}
+
+// equalRangeChecksum returns true if the checksum of the expected tuples is equal
+// to the checksum of the stored tuples of the same primary key range computed in Tarantool.
+func (b *Bridge) equalRangeChecksum(ctx context.Context, r *rule, expected [][]interface{}, keys []interface{}) (bool, error) {
+	res, err := b.tntClient.Exec(ctx, &tnt.Eval{
+		Expression: rangeChecksumExpr,
+		Tuple:      []interface{}{r.space, keys[0], keys[len(keys)-1], len(expected), len(expected[0])},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+		return false, nil
+	}
+
+	sum, _ := res.Data[0][0].(string)
+
+	return sum == rangeChecksum(expected), nil
+}
+
+// fetchedTuples returns the tuples returned by getTuplesExpr
+// cut to the length of the expected ones, the missing tuples are nil.
+func fetchedTuples(res *tnt.Result, expected [][]interface{}) [][]interface{} {
+	actual := make([][]interface{}, len(expected))
+	if len(res.Data) == 0 {
+		return actual
+	}
+
+	for i, v := range res.Data[0] {
+		if i >= len(actual) {
+			break
+		}
+
+		t, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+
+		// Only the mapped fields are compared.
+		if len(t) > len(expected[i]) {
+			t = t[:len(expected[i])]
+		}
+		actual[i] = t
+	}
+
+	return actual
+}
+
+// verifyExtra walks the space and looks for the tuples absent in MySQL.
+func (b *Bridge) verifyExtra(ctx context.Context, conn *client.Conn, r *rule, report *VerifyReport, repair bool) error {
+	chunkSize := b.snapshotCfg.chunkSize
+
+	after := []interface{}{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		res, err := b.tntClient.Exec(ctx, &tnt.Eval{
+			Expression: selectKeysExpr,
+			Tuple:      []interface{}{r.space, after, chunkSize},
+		})
+		if err != nil {
+			return err
+		}
+
+		var keys [][]interface{}
+		if len(res.Data) > 0 {
+			for _, v := range res.Data[0] {
+				key, ok := v.([]interface{})
+				if !ok || len(key) != len(r.pks) {
+					return fmt.Errorf("primary index of space %s does not match primary keys of the table", r.space)
+				}
+
+				keys = append(keys, key)
+			}
+		}
+
+		if len(keys) == 0 {
+			return nil
+		}
+
+		existing, err := selectExistingKeys(conn, r, keys)
+		if err != nil {
+			return err
+		}
+
+		var extra [][]interface{}
+		for _, key := range keys {
+			if _, ok := existing[keyString(key)]; !ok {
+				report.addExtra(key)
+				extra = append(extra, key)
+			}
+		}
+
+		if repair && len(extra) > 0 {
+			msg, err := b.readRepair(conn, r, extra)
+			if err != nil {
+				return err
+			}
+
+			b.syncCh <- msg
+		}
+
+		if len(keys) < chunkSize {
+			return nil
+		}
+
+		after = keys[len(keys)-1]
+	}
+}
+
+// selectExistingKeys returns the set of the given primary keys existing in MySQL.
+// The keys of the rows not matching the filter of the rule are not included.
+func selectExistingKeys(conn *client.Conn, r *rule, keys [][]interface{}) (map[string]struct{}, error) {
+	onlyPK := r.filter == nil
+	rows, err := selectByKeys(conn, r, keys, onlyPK)
+	if err != nil {
+		return nil, err
+	}
+
+	// The keys are cast as the keys of the tuples.
+	existing := make(map[string]struct{}, len(rows))
+	for _, row := range rows {
+		key := make([]interface{}, 0, len(r.pks))
+		if onlyPK {
+			for i, pk := range r.pks {
+				v, err := pk.cast(row[i])
+				if err != nil {
+					return nil, err
+				}
+
+				key = append(key, v)
+			}
+		} else {
+			if !r.match(row) {
+				continue
+			}
+
+			args, ok, err := makeArgs(r, r.pks, row)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			for _, arg := range args {
+				key = append(key, arg.value)
+			}
+		}
+
+		existing[keyString(key)] = struct{}{}
+	}
+
+	return existing, nil
+}
+
+// selectByKeys returns the rows by the tuple keys of the rule, the rows contain
+// only key columns if onlyPK is true, all columns otherwise.
+func selectByKeys(conn *client.Conn, r *rule, keys [][]interface{}, onlyPK bool) ([][]interface{}, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	table := r.tableInfo
+
+	pks := make([]string, 0, len(r.pks))
+	for _, pk := range r.pks {
+		pks = append(pks, quoteName(table.Columns[pk.colIndex].Name))
+	}
+
+	cols := pks
+	if !onlyPK {
+		cols = make([]string, 0, len(table.Columns))
+		for _, col := range table.Columns {
+			cols = append(cols, quoteName(col.Name))
+		}
+	}
+
+	// DECIMAL keys are compared exactly, not as doubles.
+	params := make([]string, 0, len(r.pks))
+	for _, pk := range r.pks {
+		if pk.vType == typeDecimal {
+			params = append(params, "CAST(? AS DECIMAL(65, 30))")
+		} else {
+			params = append(params, "?")
+		}
+	}
+
+	placeholders := "(" + strings.Join(params, ", ") + ")"
+	tuples := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, len(keys)*len(pks))
+	for _, key := range keys {
+		tuples = append(tuples, placeholders)
+		for i, v := range key {
+			args = append(args, r.pks[i].sourceValue(v))
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE (%s) IN (%s)",
+		strings.Join(cols, ", "), quoteName(table.Schema), quoteName(table.Name),
+		strings.Join(pks, ", "), strings.Join(tuples, ", "))
+
+	res, err := conn.Execute(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]interface{}, 0, len(res.Values))
+	for _, values := range res.Values {
+		if !onlyPK {
+			row, err := snapshotRow(table, values)
+			if err != nil {
+				return nil, err
+			}
+
+			rows = append(rows, row)
+
+			continue
+		}
+
+		key := make([]interface{}, 0, len(values))
+		for i, v := range values {
+			pk, err := snapshotValue(&table.Columns[r.pks[i].colIndex], v.Value())
+			if err != nil {
+				return nil, err
+			}
+
+			key = append(key, pk)
+		}
+
+		rows = append(rows, key)
+	}
+
+	return rows, nil
+}
+
+// normalizeValue brings the value to the form comparable
+// regardless of the way it is decoded.
+func normalizeValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return normalizeInt(int64(n))
+	case int8:
+		return normalizeInt(int64(n))
+	case int16:
+		return normalizeInt(int64(n))
+	case int32:
+		return normalizeInt(int64(n))
+	case int64:
+		return normalizeInt(n)
+	case uint:
+		return uint64(n)
+	case uint8:
+		return uint64(n)
+	case uint16:
+		return uint64(n)
+	case uint32:
+		return uint64(n)
+	case float32:
+		return normalizeFloat(float64(n))
+	case float64:
+		return normalizeFloat(n)
+	case []byte:
+		return string(n)
+	case decimal.Decimal:
+		return exactDecimal(n.String())
+	case *decimalValue, *msgp.RawExtension:
+		// Decimals of any scale are compared by value.
+		if d, ok := extensionDecimal(n); ok {
+			return exactDecimal(d.String())
+		}
+	}
+
+	return v
+}
+
+// exactDecimal is the decimal notation without trailing zeros, e.g. "10.5".
+type exactDecimal string
+
+func normalizeInt(n int64) interface{} {
+	if n >= 0 {
+		return uint64(n)
+	}
+
+	return n
+}
+
+func normalizeFloat(f float64) interface{} {
+	// Tarantool may store the integral float as an integer.
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return normalizeInt(int64(f))
+	}
+
+	return f
+}
+
+func writeValue(sb *strings.Builder, v interface{}) {
+	switch n := normalizeValue(v).(type) {
+	case nil:
+		sb.WriteString("n")
+	case uint64:
+		sb.WriteString("u")
+		sb.WriteString(strconv.FormatUint(n, 10))
+	case int64:
+		sb.WriteString("i")
+		sb.WriteString(strconv.FormatInt(n, 10))
+	case float64:
+		// The precision of %.17g is enough to tell apart any doubles.
+		sb.WriteString("f")
+		sb.WriteString(strconv.FormatFloat(n, 'g', 17, 64))
+	case string:
+		// The length prefix keeps the separators inside the string unambiguous.
+		sb.WriteString("s")
+		sb.WriteString(strconv.Itoa(len(n)))
+		sb.WriteString(":")
+		sb.WriteString(n)
+	case bool:
+		sb.WriteString("b")
+		sb.WriteString(strconv.FormatBool(n))
+	case exactDecimal:
+		sb.WriteString("d")
+		sb.WriteString(string(n))
+	case []interface{}:
+		sb.WriteString("a[")
+		for i, e := range n {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			writeValue(sb, e)
+		}
+		sb.WriteString("]")
+	case map[string]interface{}:
+		writeMap(sb, n)
+	case msgp.Extension:
+		// The extension values written by the replicator and
+		// the raw ones read from Tarantool are compared by payload.
+		data := make([]byte, n.Len())
+		if err := n.MarshalBinaryTo(data); err != nil {
+			sb.WriteString(fmt.Sprintf("%T:%v", n, n))
+
+			return
+		}
+		sb.WriteString("x")
+		sb.WriteString(strconv.Itoa(int(n.ExtensionType())))
+		sb.WriteString(":")
+		sb.WriteString(hex.EncodeToString(data))
+	default:
+		sb.WriteString(fmt.Sprintf("%T:%v", n, n))
+	}
+}
+
+// writeMap writes the map with the keys sorted.
+func writeMap(sb *strings.Builder, m map[string]interface{}) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sb.WriteString("m{")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		writeValue(sb, k)
+		sb.WriteByte(':')
+		writeValue(sb, m[k])
+	}
+	sb.WriteString("}")
+}
+
+func keyString(values []interface{}) string {
+	var sb strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		writeValue(&sb, v)
+	}
+
+	return sb.String()
+}
+
+func equalTuples(a, b []interface{}) bool {
+	return keyString(a) == keyString(b)
+}
+
+// rangeChecksum returns the checksum of the tuples computed the same way as by rangeChecksumExpr.
+func rangeChecksum(tuples [][]interface{}) string {
+	h := md5.New() //nolint:gosec // not used for security
+	for i, t := range tuples {
+		if i > 0 {
+			_, _ = h.Write([]byte{';'})
+		}
+		_, _ = io.WriteString(h, keyString(t))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,135 @@
+package bridge
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func Test_keyString(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []interface{}
+		b    []interface{}
+		want bool
+	}{
+		{
+			name: "SignedAndUnsigned",
+			a:    []interface{}{int64(10), "bob"},
+			b:    []interface{}{uint64(10), "bob"},
+			want: true,
+		},
+		{
+			name: "IntegralFloat",
+			a:    []interface{}{float64(10)},
+			b:    []interface{}{uint64(10)},
+			want: true,
+		},
+		{
+			name: "Bytes",
+			a:    []interface{}{[]byte("bob")},
+			b:    []interface{}{"bob"},
+			want: true,
+		},
+		{
+			name: "Negative",
+			a:    []interface{}{-1},
+			b:    []interface{}{int64(-1)},
+			want: true,
+		},
+		{
+			name: "NumberAndString",
+			a:    []interface{}{uint64(10)},
+			b:    []interface{}{"10"},
+			want: false,
+		},
+		{
+			name: "Null",
+			a:    []interface{}{nil},
+			b:    []interface{}{""},
+			want: false,
+		},
+		{
+			name: "Map",
+			a:    []interface{}{map[string]interface{}{"b": []interface{}{int64(1)}, "a": nil}},
+			b:    []interface{}{map[string]interface{}{"a": nil, "b": []interface{}{uint64(1)}}},
+			want: true,
+		},
+		{
+			name: "DecimalScale",
+			a:    []interface{}{&decimalValue{text: "10.50", data: []byte{0x02, 0x01, 0x05, 0x0c}}},
+			b:    []interface{}{&msgp.RawExtension{Type: decimalExtType, Data: []byte{0x01, 0x10, 0x5c}}},
+			want: true,
+		},
+		{
+			name: "DecimalPrecise",
+			a:    []interface{}{decimal.RequireFromString("12345678901234567890.12")},
+			b:    []interface{}{decimal.RequireFromString("12345678901234567890.13")},
+			want: false,
+		},
+		{
+			name: "AdjacentFloats",
+			a:    []interface{}{0.1},
+			b:    []interface{}{math.Nextafter(0.1, 1)},
+			want: false,
+		},
+		{
+			name: "Bool",
+			a:    []interface{}{true},
+			b:    []interface{}{"true"},
+			want: false,
+		},
+		{
+			name: "Separator",
+			a:    []interface{}{"a,sb"},
+			b:    []interface{}{"a", "b"},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, keyString(tt.a) == keyString(tt.b))
+		})
+	}
+}
+
+func Test_rangeChecksum(t *testing.T) {
+	tuples := [][]interface{}{
+		{int64(1), "bob", nil},
+		{-2, 1.5, true},
+	}
+
+	// The text hashed by rangeChecksumExpr in Tarantool.
+	sum := md5.Sum([]byte("u1,s3:bob,n;i-2,f1.5,btrue"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), rangeChecksum(tuples))
+
+	assert.Equal(t, rangeChecksum(tuples), rangeChecksum([][]interface{}{
+		{uint64(1), []byte("bob"), nil},
+		{int64(-2), float32(1.5), true},
+	}))
+	assert.NotEqual(t, rangeChecksum(tuples), rangeChecksum(tuples[:1]))
+	assert.NotEqual(t, rangeChecksum(tuples), rangeChecksum([][]interface{}{
+		{int64(1), "Bob", nil},
+		{-2, 1.5, true},
+	}))
+}
+
+func TestVerifyReport_Limit(t *testing.T) {
+	r := &VerifyReport{}
+	assert.True(t, r.Consistent())
+
+	for i := 0; i < maxReportedKeys+1; i++ {
+		r.addMissing([]interface{}{i})
+	}
+
+	assert.False(t, r.Consistent())
+	assert.EqualValues(t, maxReportedKeys+1, r.MissingCount)
+	assert.Len(t, r.Missing, maxReportedKeys)
+}
@@ -8,9 +8,27 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	FlavorMySQL   = "mysql"
+	FlavorMariaDB = "mariadb"
+)
+
+const (
+	DumpModeMysqldump = "mysqldump"
+	DumpModeNative    = "native"
+)
+
+const (
+	StateStorageFile      = "file"
+	StateStorageTarantool = "tarantool"
+)
+
 const (
 	defaultListenAddr         = ":8080"
 	defaultDataFile           = "/etc/mysql-tarantool-replicator/state.info"
+	defaultStateStorage       = StateStorageFile
+	defaultStateSpace         = "replicator_state"
+	defaultStateKey           = "default"
 	defaultHealthSBM          = 10
 	defaultLogLevel           = "debug"
 	defaultSysLogEnabled      = false
@@ -20,7 +38,12 @@ const (
 	defaultLogFileMaxBackups  = 3
 	defaultLogFileMaxAge      = 5
 	defaultGTIDMode           = true
+	defaultMaxTxRows          = 10000
 	defaultDumpExecPath       = "/usr/bin/mysqldump"
+	defaultDumpMode           = DumpModeMysqldump
+	defaultDumpChunkSize      = 1000
+	defaultDumpParallelism    = 2
+	defaultFlavor             = FlavorMySQL
 	defaultCharset            = "utf8mb4_unicode_ci"
 	defaultConnectTimeout     = 500 * time.Millisecond
 	defaultRequestTimeout     = 1 * time.Second
@@ -35,6 +58,14 @@ type Config struct {
 		// GTIDMode indicates when to use GTID-based replication
 		// or binlog file position.
 		GTIDMode bool `yaml:"gtid_mode"`
+		// ExactlyOnce indicates to apply the changes of each MySQL transaction
+		// and the position following them in one Tarantool transaction.
+		// Requires the position to be stored in Tarantool.
+		ExactlyOnce bool `yaml:"exactly_once"`
+		// MaxTxRows is the maximum number of rows of one MySQL transaction
+		// buffered to apply them atomically. Larger transactions are applied in parts.
+		// Zero means no limit.
+		MaxTxRows int `yaml:"max_tx_rows"`
 		// ConnectionSrc is the options to connect to MySQL.
 		ConnectionSrc SourceConnectConfig `yaml:"mysql"`
 		// ConnectionDest is the options to connect to Tarantool.
@@ -47,10 +78,23 @@ type Config struct {
 type AppConfig struct {
 	ListenAddr string  `yaml:"listen_addr"`
 	DataFile   string  `yaml:"data_file"`
+	State      State   `yaml:"state"`
 	Health     Health  `yaml:"health"`
 	Logging    Logging `yaml:"logging"`
 }
 
+type State struct {
+	// Storage is where to keep the replication position:
+	// "file" stores it in DataFile, "tarantool" stores it in Space.
+	Storage string `yaml:"storage"`
+	// Space is the Tarantool space to store the replication position in.
+	// The space is created automatically if it does not exist.
+	Space string `yaml:"space"`
+	// Key identifies the position of this replicator in the Space,
+	// so several replicators may share one Tarantool instance.
+	Key string `yaml:"key"`
+}
+
 type Health struct {
 	SecondsBehindMaster int `yaml:"seconds_behind_master"`
 }
@@ -73,6 +117,10 @@ func (c *AppConfig) withDefaults() {
 	c.ListenAddr = defaultListenAddr
 	c.DataFile = defaultDataFile
 
+	c.State.Storage = defaultStateStorage
+	c.State.Space = defaultStateSpace
+	c.State.Key = defaultStateKey
+
 	c.Health.SecondsBehindMaster = defaultHealthSBM
 
 	c.Logging.Level = defaultLogLevel
@@ -86,6 +134,14 @@ func (c *AppConfig) withDefaults() {
 
 type SourceConnectConfig struct {
 	Dump struct {
+		// Mode is the way to load the initial data:
+		// "mysqldump" runs the external binary, "native" reads tables
+		// by primary key chunks with consistent snapshot.
+		Mode string `yaml:"mode"`
+		// ChunkSize is the number of rows read at once in native mode.
+		ChunkSize int `yaml:"chunk_size"`
+		// Parallelism is the number of tables read concurrently in native mode.
+		Parallelism int `yaml:"parallelism"`
 		// ExecPath is absolute path to mysqldump binary.
 		ExecPath string `yaml:"dump_exec_path"`
 		// SkipMasterData set true if you have no privilege to use `--master-data`.
@@ -93,6 +149,8 @@ type SourceConnectConfig struct {
 		// ExtraOptions for mysqldump CLI.
 		ExtraOptions []string `yaml:"extra_options"`
 	} `yaml:"dump"`
+	// Flavor is the source server type: "mysql" or "mariadb".
+	Flavor   string `yaml:"flavor"`
 	Addr     string `yaml:"addr"`
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
@@ -104,7 +162,11 @@ func (c *SourceConnectConfig) withDefaults() {
 		return
 	}
 
+	c.Dump.Mode = defaultDumpMode
+	c.Dump.ChunkSize = defaultDumpChunkSize
+	c.Dump.Parallelism = defaultDumpParallelism
 	c.Dump.ExecPath = defaultDumpExecPath
+	c.Flavor = defaultFlavor
 	c.Charset = defaultCharset
 }
 
@@ -115,6 +177,9 @@ type DestConnectConfig struct {
 	MaxRetries     int           `yaml:"max_retries"`
 	ConnectTimeout time.Duration `yaml:"connect_timeout"`
 	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// CreateSpaces indicates to create the missing spaces of the mappings
+	// on start, the format and the primary index follow the tables.
+	CreateSpaces bool `yaml:"create_spaces"`
 }
 
 func (c *DestConnectConfig) withDefaults() {
@@ -128,19 +193,71 @@ func (c *DestConnectConfig) withDefaults() {
 
 type Mapping struct {
 	Source struct {
-		Schema  string   `yaml:"schema"`
-		Table   string   `yaml:"table"`
-		Columns []string `yaml:"columns"`
+		Schema string `yaml:"schema"`
+		Table  string `yaml:"table"`
+		// Regex indicates that Schema and Table are regular expressions
+		// matching the whole names, so all the matching tables
+		// are replicated to one space.
+		Regex   bool     `yaml:"regex,omitempty"`
+		Columns []string `yaml:"columns,omitempty"`
+		// Where is SQL predicate over the columns of the table, e.g. "deleted_at IS NULL",
+		// only the matching rows are replicated. An update moving the row out of
+		// the predicate deletes the tuple, moving it in inserts the tuple.
+		Where string `yaml:"where,omitempty"`
 	} `yaml:"source"`
 
 	Dest struct {
-		Space  string                   `yaml:"space"`
-		Column map[string]MappingColumn `yaml:"column"`
+		Space string `yaml:"space"`
+		// Key is the list of columns forming the primary key of the tuple,
+		// the primary key of MySQL table by default. The columns must be unique in MySQL.
+		Key    []string                 `yaml:"key,omitempty"`
+		Column map[string]MappingColumn `yaml:"column,omitempty"`
+		// OnConflict is the policy to resolve conflicts on inserting
+		// an existing tuple or updating a missing one:
+		// "error" (default), "replace", "upsert" or "skip".
+		OnConflict string `yaml:"on_conflict,omitempty"`
+		// OnSchemaChange is the policy applied when a mapped column is dropped
+		// from MySQL table: "fail" (default) stops the replication, "pause" stops
+		// replicating the table to the space until the column is back,
+		// "drop_field" keeps replicating the field as null or on_null value.
+		OnSchemaChange string `yaml:"on_schema_change,omitempty"`
+		// OnTruncate is the policy applied on TRUNCATE of MySQL table:
+		// "truncate" (default) truncates the space, "ignore" keeps the tuples,
+		// "fail" stops the replication. Truncate of a space shared by several
+		// tables stops the replication.
+		OnTruncate string `yaml:"on_truncate,omitempty"`
+		// OnDrop is the policy applied on DROP or RENAME of MySQL table:
+		// "pause" (default) stops replicating the table until it is created again,
+		// "fail" stops the replication.
+		OnDrop string `yaml:"on_drop,omitempty"`
 	} `yaml:"dest"`
 }
 
 type MappingColumn struct {
-	Cast   string      `yaml:"cast"`
+	Cast   string      `yaml:"cast,omitempty"`
+	OnNull interface{} `yaml:"on_null,omitempty"`
+	// Strict indicates to treat negative values cast to unsigned, overflows
+	// and lossy float to integer conversions as invalid values. Implied by OnInvalid.
+	Strict bool `yaml:"strict,omitempty"`
+	// OnInvalid is the policy applied to the invalid values in strict mode:
+	// "fail" (default) stops the replication, "clamp" replaces the value by
+	// the nearest one in range, "default" replaces it by OnNull value,
+	// "log" skips the row and logs it.
+	OnInvalid string `yaml:"on_invalid,omitempty"`
+	// TimeZone is the time zone of DATETIME and DATE values cast to
+	// the temporal types: "UTC" (default), an offset like "+03:00"
+	// or a name like "Europe/Moscow". TIMESTAMP values are always read in UTC.
+	TimeZone string `yaml:"time_zone,omitempty"`
+	// Extract is the list of JSON paths of the column extracted
+	// into their own fields following the field of the column.
+	Extract []MappingJSONPath `yaml:"extract,omitempty"`
+}
+
+type MappingJSONPath struct {
+	// Field is the name of the space field.
+	Field string `yaml:"field"`
+	// Path is JSON path of the value, e.g. "$.address.city" or "$.tags[0]".
+	Path   string      `yaml:"path"`
 	OnNull interface{} `yaml:"on_null,omitempty"`
 }
 
@@ -177,6 +294,7 @@ func (c *Config) withDefaults() {
 	app.withDefaults()
 
 	c.Replication.GTIDMode = defaultGTIDMode
+	c.Replication.MaxTxRows = defaultMaxTxRows
 
 	srcConn := &c.Replication.ConnectionSrc
 	srcConn.withDefaults()
@@ -26,6 +26,11 @@ func TestReadFromFile_ValidPath(t *testing.T) {
 	assert.Equal(t, ":8081", cfg.App.ListenAddr)
 	assert.Equal(t, "/etc/mysql-tarantool-replicator/state.info", cfg.App.DataFile)
 
+	stateCfg := cfg.App.State
+	assert.Equal(t, StateStorageTarantool, stateCfg.Storage)
+	assert.Equal(t, "mysql_state", stateCfg.Space)
+	assert.Equal(t, "city", stateCfg.Key)
+
 	healthCfg := cfg.App.Health
 	assert.Equal(t, 5, healthCfg.SecondsBehindMaster)
 
@@ -41,11 +46,17 @@ func TestReadFromFile_ValidPath(t *testing.T) {
 	require.NotNil(t, cfg.Replication.ServerID)
 	assert.EqualValues(t, 100, *cfg.Replication.ServerID)
 	assert.True(t, cfg.Replication.GTIDMode)
+	assert.True(t, cfg.Replication.ExactlyOnce)
+	assert.Equal(t, 5000, cfg.Replication.MaxTxRows)
 
 	connSrc := cfg.Replication.ConnectionSrc
+	assert.Equal(t, DumpModeNative, connSrc.Dump.Mode)
+	assert.Equal(t, 500, connSrc.Dump.ChunkSize)
+	assert.Equal(t, 4, connSrc.Dump.Parallelism)
 	assert.Equal(t, "/usr/bin/mysqldump", connSrc.Dump.ExecPath)
 	assert.False(t, connSrc.Dump.SkipMasterData)
 	assert.Equal(t, []string{"--column-statistics=0"}, connSrc.Dump.ExtraOptions)
+	assert.Equal(t, FlavorMariaDB, connSrc.Flavor)
 	assert.Equal(t, "127.0.0.1:3306", connSrc.Addr)
 	assert.Equal(t, "repl", connSrc.User)
 	assert.Equal(t, "repl", connSrc.Password)
@@ -60,13 +71,14 @@ func TestReadFromFile_ValidPath(t *testing.T) {
 	assert.Equal(t, 500*time.Millisecond, destSrc.RequestTimeout)
 
 	mappings := cfg.Replication.Mappings
-	require.Len(t, mappings, 1)
+	require.Len(t, mappings, 2)
 
 	mapping := mappings[0]
 	assert.Equal(t, "city", mapping.Source.Schema)
 	assert.Equal(t, "users", mapping.Source.Table)
 	assert.Equal(t, []string{"username", "password", "email"}, mapping.Source.Columns)
 	assert.Equal(t, "users", mapping.Dest.Space)
+	assert.Equal(t, "replace", mapping.Dest.OnConflict)
 	assert.Len(t, mapping.Dest.Column, 3)
 	columnMapping, ok := mapping.Dest.Column["attempts"]
 	if assert.True(t, ok) {
@@ -83,4 +95,10 @@ func TestReadFromFile_ValidPath(t *testing.T) {
 		assert.Equal(t, "unsigned", columnMapping.Cast)
 		assert.Nil(t, columnMapping.OnNull)
 	}
+	assert.Empty(t, mapping.Dest.Key)
+
+	mapping = mappings[1]
+	assert.Equal(t, "users", mapping.Source.Table)
+	assert.Equal(t, "users_by_email", mapping.Dest.Space)
+	assert.Equal(t, []string{"email"}, mapping.Dest.Key)
 }
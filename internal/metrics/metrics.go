@@ -28,12 +28,47 @@ var (
 		Name:      "state",
 		Help:      "The replication running state: 0=stopped, 1=dumping, 2=running",
 	})
+
+	dumpRows = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "mysql2tarantool",
+		Name:      "dump_rows",
+		Help:      "Number of rows copied by the initial dump per table",
+	}, []string{"table"})
+
+	dumpDone = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "mysql2tarantool",
+		Name:      "dump_done",
+		Help:      "Whether the initial dump of the table is completed: 0=no, 1=yes",
+	}, []string{"table"})
+
+	repairedTuples = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mysql2tarantool",
+		Name:      "repaired_tuples",
+		Help:      "Number of divergent tuples repaired after verification per table and action: insert or delete",
+	}, []string{"table", "action"})
+
+	ddlActions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mysql2tarantool",
+		Name:      "ddl_actions",
+		Help:      "Number of DDL statements of the mapped tables acted upon per table, statement and action",
+	}, []string{"table", "statement", "action"})
+
+	invalidValues = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mysql2tarantool",
+		Name:      "invalid_values",
+		Help:      "Number of values which could not be cast exactly per table, column and policy",
+	}, []string{"table", "column", "policy"})
 )
 
 func Init() {
 	prometheus.MustRegister(secondsBehindMaster)
 	prometheus.MustRegister(replState)
 	prometheus.MustRegister(syncedSecondsAgo)
+	prometheus.MustRegister(dumpRows)
+	prometheus.MustRegister(dumpDone)
+	prometheus.MustRegister(repairedTuples)
+	prometheus.MustRegister(ddlActions)
+	prometheus.MustRegister(invalidValues)
 }
 
 func SetSecondsBehindMaster(value uint32) {
@@ -47,3 +82,24 @@ func SetSyncedSecondsAgo(sec int64) {
 func SetReplicationState(state ReplState) {
 	replState.Set(float64(state))
 }
+
+func SetDumpProgress(table string, rows uint64, done bool) {
+	dumpRows.WithLabelValues(table).Set(float64(rows))
+	if done {
+		dumpDone.WithLabelValues(table).Set(1)
+	} else {
+		dumpDone.WithLabelValues(table).Set(0)
+	}
+}
+
+func AddRepairedTuples(table, action string, n int) {
+	repairedTuples.WithLabelValues(table, action).Add(float64(n))
+}
+
+func AddDDLAction(table, statement, action string) {
+	ddlActions.WithLabelValues(table, statement, action).Inc()
+}
+
+func AddInvalidValue(table, column, policy string) {
+	invalidValues.WithLabelValues(table, column, policy).Inc()
+}